@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Deleplace/verboten/textgame"
+)
+
+// clearScreen is the ANSI sequence that clears the terminal, so that the
+// guessers don't see the card.
+const clearScreen = "\033[H\033[2J"
+
+// maxJudgeErrors is the number of failed rulings of the judge after which a
+// round is given up.
+const maxJudgeErrors = 3
+
+// parsePlayers returns the non-empty names of a comma-separated list.
+func parsePlayers(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// playHotSeat plays the cards with human players taking turns at the same
+// terminal: in each round one player describes, and the others guess in
+// turn. The AI is only the judge of the proscribed words.
+//
+// When a word is guessed, both the describer and the guesser score a point.
+// The game ends early, with the scores so far, when the input runs out.
+func playHotSeat(ctx context.Context, reader *bufio.Reader, players []string, cards []textgame.Card, currentPhrases uiPhrases) error {
+	scores := make(map[string]int, len(players))
+	var err error
+	for i := range cards {
+		describer := players[i%len(players)]
+		// The guessers, starting with the player after the describer
+		var guessers []string
+		for j := 1; j < len(players); j++ {
+			guessers = append(guessers, players[(i+j)%len(players)])
+		}
+
+		fmt.Fprintf(out, currentPhrases.roundHeader, i+1, len(cards))
+		fmt.Fprintf(out, currentPhrases.describerTurn, describer)
+		var guesser string
+		guesser, err = playHotSeatRound(ctx, reader, &cards[i], describer, guessers, currentPhrases)
+		if guesser != "" {
+			scores[describer]++
+			scores[guesser]++
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	fmt.Fprint(out, currentPhrases.hotSeatSummary)
+	for _, player := range players {
+		fmt.Fprintf(out, currentPhrases.playerScore, player, scores[player])
+	}
+	if err == io.EOF {
+		// No more input
+		return nil
+	}
+	return err
+}
+
+// playHotSeatRound lets the describer make the guessers find the word, and
+// returns the player who found it, or "" if the round is lost.
+// It returns io.EOF if the input ran out before the end of the round.
+func playHotSeatRound(ctx context.Context, reader *bufio.Reader, gameWord *textgame.Card, describer string, guessers []string, currentPhrases uiPhrases) (winner string, err error) {
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, currentPhrases.wordToDescribe, gameWord.Word)
+	fmt.Fprintf(out, currentPhrases.forbiddenWordsAre, strings.Join(gameWord.Forbidden, ", "))
+	printRules(currentPhrases)
+	fmt.Fprint(out, currentPhrases.hideCard)
+	if _, err := readLine(reader); err != nil {
+		return "", err
+	}
+	fmt.Fprint(out, clearScreen)
+
+	judgeErrors := 0
+	for attempt := 0; attempt < 3; {
+		fmt.Fprintf(out, currentPhrases.giveClue, describer)
+		description, err := readLine(reader)
+		if err != nil {
+			return "", err
+		}
+
+		if v := textgame.CheckRules(gameRules, description); v != nil {
+			printRuleViolation(v, currentPhrases)
+			fmt.Fprintf(out, currentPhrases.wordWas, gameWord.Word)
+			return "", nil
+		}
+
+		verdict, err := judge.SaidForbidden(ctx, gameWord, description)
+		if err != nil {
+			// This clue doesn't count, unless the judge keeps failing
+			fmt.Fprintf(out, currentPhrases.modelError, err)
+			judgeErrors++
+			if judgeErrors == maxJudgeErrors {
+				break
+			}
+			continue
+		}
+		printJudgeNotes(verdict)
+		if verdict.Lost {
+			printViolation(verdict, currentPhrases)
+			fmt.Fprintf(out, currentPhrases.wordWas, gameWord.Word)
+			return "", nil
+		}
+
+		guesser := guessers[attempt%len(guessers)]
+		fmt.Fprintf(out, currentPhrases.playerGuess, guesser)
+		guess, err := readLine(reader)
+		if err != nil {
+			return "", err
+		}
+		if gameWord.IsWinning(guess) {
+			fmt.Fprintf(out, currentPhrases.playerGuessed, guesser, describer, guesser)
+			return guesser, nil
+		}
+		attempt++
+	}
+
+	fmt.Fprintf(out, currentPhrases.nobodyGuessed, gameWord.Word)
+	return "", nil
+}
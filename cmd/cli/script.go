@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Deleplace/verboten/deck"
+	"github.com/Deleplace/verboten/textgame"
+)
+
+// out receives the text for the human player. It is discarded in JSON mode.
+var out io.Writer = os.Stdout
+
+// The reasons why a round ended, as in the web game.
+const (
+	reasonGuessed       = "guessed"
+	reasonForbidden     = "forbidden"
+	reasonRule          = "rule"
+	reasonNoMoreGuesses = "noMoreGuesses"
+	reasonEndOfInput    = "endOfInput"
+)
+
+// event is a line of the JSON output.
+type event struct {
+	// Type is "verdict", "guess", "error", "round", "debrief", "match" or "feedback".
+	Type  string `json:"type"`
+	Round int    `json:"round,omitempty"`
+	Card  string `json:"card,omitempty"`
+
+	// verdict and guess
+	Description string            `json:"description,omitempty"`
+	Verdict     *textgame.Verdict `json:"verdict,omitempty"`
+	// Violation is the broken rule, with -rules.
+	Violation *textgame.Violation `json:"violation,omitempty"`
+	Guess     string              `json:"guess,omitempty"`
+	// Candidates are the ranked guesses, with -candidates.
+	Candidates []textgame.Candidate `json:"candidates,omitempty"`
+	Error      string               `json:"error,omitempty"`
+
+	// debrief
+	Debrief *textgame.Debrief `json:"debrief,omitempty"`
+
+	// feedback, in the cross-lingual mode
+	Feedback *textgame.LanguageFeedback `json:"feedback,omitempty"`
+
+	// round
+	Word   string `json:"word,omitempty"`
+	Won    *bool  `json:"won,omitempty"`
+	Reason string `json:"reason,omitempty"`
+
+	// match
+	Score  *int  `json:"score,omitempty"`
+	Rounds int   `json:"rounds,omitempty"`
+	Seed   int64 `json:"seed,omitempty"`
+}
+
+// emit writes the event as a JSON line, in JSON mode.
+func emit(ev event) {
+	if !*jsonOutput {
+		return
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(append(data, '\n'))
+}
+
+// readLine returns the next line of input, without the trailing newline.
+// It returns io.EOF when there is no more input.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		// Last line, without newline
+		err = nil
+	}
+	return strings.TrimSpace(line), err
+}
+
+// openScript returns a reader of the descriptions of the script file,
+// one per line. Empty lines and lines starting with # are skipped.
+func openScript(filename string) (*bufio.Reader, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for line := range strings.Lines(string(data)) {
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		b.WriteString(line)
+	}
+	return bufio.NewReader(strings.NewReader(b.String())), nil
+}
+
+// pickCards returns the cards of the comma-separated IDs, in order.
+func pickCards(words deck.Deck, lang, ids string) ([]deck.Card, error) {
+	var cards []deck.Card
+	for id := range strings.SplitSeq(ids, ",") {
+		id = strings.TrimSpace(id)
+		found := false
+		for _, c := range words[lang] {
+			if c.ID == id {
+				cards = append(cards, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no card %q in language %q", id, lang)
+		}
+	}
+	return cards, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// spinnerFrames are drawn in turn while the judge is checking a description.
+var spinnerFrames = []rune(`|/-\`)
+
+// streamPrinter prints the guess of the AI as it streams in, followed by a
+// spinner while the judge is still checking the description.
+type streamPrinter struct {
+	mu       sync.Mutex
+	spinning bool
+	frame    int
+	done     chan struct{}
+}
+
+// startSpinner draws the spinner until stopSpinner. There is no spinner when
+// the output is not a terminal.
+func (p *streamPrinter) startSpinner() {
+	if out != os.Stdout || !isTerminal(os.Stdout) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spinning = true
+	p.done = make(chan struct{})
+	fmt.Fprint(out, string(spinnerFrames[0]))
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.mu.Lock()
+				p.frame = (p.frame + 1) % len(spinnerFrames)
+				fmt.Fprint(out, "\b"+string(spinnerFrames[p.frame]))
+				p.mu.Unlock()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// stopSpinner erases the spinner.
+func (p *streamPrinter) stopSpinner() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.spinning {
+		return
+	}
+	p.spinning = false
+	close(p.done)
+	fmt.Fprint(out, "\b \b")
+}
+
+// write prints a chunk of the guess, before the spinner.
+func (p *streamPrinter) write(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.spinning {
+		fmt.Fprint(out, "\b"+text+string(spinnerFrames[p.frame]))
+		return
+	}
+	fmt.Fprint(out, text)
+}
+
+// isTerminal tells if f is a terminal, rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -3,31 +3,22 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"os"
+	"slices"
 	"strings"
 	"time"
-	"unicode"
 
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/text/runes"
-	"golang.org/x/text/transform"
-	"golang.org/x/text/unicode/norm"
 	"google.golang.org/genai"
-)
 
-type forbiddenWord struct {
-	Word         string   `json:"word"`
-	Forbidden    []string `json:"forbidden"`
-	languageName string
-}
-type wordsByLang struct {
-	En []forbiddenWord `json:"en"`
-	Fr []forbiddenWord `json:"fr"`
-}
+	"github.com/Deleplace/verboten/deck"
+	"github.com/Deleplace/verboten/textgame"
+)
 
 type uiPhrases struct {
 	chooseLanguage          string
@@ -39,6 +30,36 @@ type uiPhrases struct {
 	aiGuess                 string
 	aiGuessedTheWord        string
 	wordWas                 string
+	roundHeader             string
+	matchSummary            string
+	roundWon                string
+	roundLost               string
+	modelError              string
+	alsoConsidered          string
+	debriefHeader           string
+	helpfulPhrases          string
+	tip                     string
+	debriefFailed           string
+	feedbackHeader          string
+	correction              string
+	vocabulary              string
+	feedbackFailed          string
+	// brokeRule are the messages of the violations of the rules, by rule
+	// name, with the fragment and the limit of the rule.
+	brokeRule map[string]string
+	// ruleTexts explain the rules, by rule name, with their limit.
+	ruleTexts map[string]string
+	rulesAre  string
+
+	// Hot-seat mode
+	describerTurn  string
+	hideCard       string
+	giveClue       string
+	playerGuess    string
+	playerGuessed  string
+	nobodyGuessed  string
+	playerScore    string
+	hotSeatSummary string
 }
 
 var phrases = map[string]uiPhrases{
@@ -49,9 +70,44 @@ var phrases = map[string]uiPhrases{
 		describeTheWord:         "\nDescribe the word.\n> ",
 		usedForbiddenWord:       "Oh! You used the proscribed word '%s'. You lose!\n",
 		usedForbiddenInflection: "Oh! You said '%s' which is too close to the proscribed word '%s'. You lose!\n",
-		aiGuess:                 "AI: %s\n",
+		aiGuess:                 "AI: ",
 		aiGuessedTheWord:        "\nThe AI guessed the word! You win!\n",
 		wordWas:                 "\nThe word was %s. You lose!\n",
+		roundHeader:             "\n=== Round %d/%d ===\n",
+		matchSummary:            "\n=== Final score: %d/%d ===\n",
+		roundWon:                "  %d. %s: won\n",
+		roundLost:               "  %d. %s: lost\n",
+		modelError:              "\nThe AI could not answer (%v). This turn doesn't count, try again.\n",
+		alsoConsidered:          "     the AI also considered: %s\n",
+		debriefHeader:           "\n--- Debrief ---\n",
+		helpfulPhrases:          "Most helpful: %s\n",
+		tip:                     "Tip: %s\n",
+		debriefFailed:           "\n(The AI could not explain this round: %v)\n",
+		feedbackHeader:          "\n=== Feedback on your descriptions ===\n",
+		correction:              "  %s → %s: %s\n",
+		vocabulary:              "Useful words: %s\n",
+		feedbackFailed:          "\n(The AI could not give feedback on your descriptions: %v)\n",
+		brokeRule: map[string]string{
+			textgame.RuleLetter:    "Oh! You said '%s', which contains the letter '%s'. You lose!\n",
+			textgame.RuleMaxWords:  "Oh! You said '%s', which is more than %s words. You lose!\n",
+			textgame.RuleMaxLength: "Oh! You said '%s', which is longer than %s letters. You lose!\n",
+			textgame.RuleOneWord:   "Oh! You said '%s', but the clues must be a single word. You lose!\n",
+		},
+		ruleTexts: map[string]string{
+			textgame.RuleLetter:    "no word containing the letter '%s'",
+			textgame.RuleMaxWords:  "at most %s words per description",
+			textgame.RuleMaxLength: "no word longer than %s letters",
+			textgame.RuleOneWord:   "one-word clues only",
+		},
+		rulesAre:       "The rules are: %s\n",
+		describerTurn:  "%s describes. Everyone else, look away!\n",
+		hideCard:       "\nPress Enter to hide the card and start.",
+		giveClue:       "\n%s, give a clue.\n> ",
+		playerGuess:    "%s, your guess: ",
+		playerGuessed:  "\n%s guessed the word! One point for %s and for %s.\n",
+		nobodyGuessed:  "\nNobody guessed. The word was %s.\n",
+		playerScore:    "  %s: %d\n",
+		hotSeatSummary: "\n=== Final scores ===\n",
 	},
 	"fr": {
 		chooseLanguage:          "Choisissez votre langue (en/fr): ",
@@ -60,17 +116,83 @@ var phrases = map[string]uiPhrases{
 		describeTheWord:         "\nDécrivez le mot.\n> ",
 		usedForbiddenWord:       "Oh! Vous avez utilisé le mot prohibé '%s'. Vous avez perdu !\n",
 		usedForbiddenInflection: "Oh! Vous avez dit '%s' qui est trop proche du mot prohibé '%s'. Vous avez perdu !\n",
-		aiGuess:                 "IA : %s\n",
+		aiGuess:                 "IA : ",
 		aiGuessedTheWord:        "\nL'IA a deviné le mot ! Vous avez gagné !\n",
 		wordWas:                 "\nLe mot était %s. Vous avez perdu !\n",
+		roundHeader:             "\n=== Manche %d/%d ===\n",
+		matchSummary:            "\n=== Score final : %d/%d ===\n",
+		roundWon:                "  %d. %s : gagné\n",
+		roundLost:               "  %d. %s : perdu\n",
+		modelError:              "\nL'IA n'a pas pu répondre (%v). Ce tour ne compte pas, réessayez.\n",
+		alsoConsidered:          "     l'IA a aussi envisagé : %s\n",
+		debriefHeader:           "\n--- Débriefing ---\n",
+		helpfulPhrases:          "Le plus utile : %s\n",
+		tip:                     "Conseil : %s\n",
+		debriefFailed:           "\n(L'IA n'a pas pu expliquer cette manche : %v)\n",
+		feedbackHeader:          "\n=== Retour sur vos descriptions ===\n",
+		correction:              "  %s → %s : %s\n",
+		vocabulary:              "Mots utiles : %s\n",
+		feedbackFailed:          "\n(L'IA n'a pas pu commenter vos descriptions : %v)\n",
+		brokeRule: map[string]string{
+			textgame.RuleLetter:    "Oh! Vous avez dit '%s', qui contient la lettre '%s'. Vous avez perdu !\n",
+			textgame.RuleMaxWords:  "Oh! Vous avez dit '%s', qui fait plus de %s mots. Vous avez perdu !\n",
+			textgame.RuleMaxLength: "Oh! Vous avez dit '%s', qui fait plus de %s lettres. Vous avez perdu !\n",
+			textgame.RuleOneWord:   "Oh! Vous avez dit '%s', mais les indices doivent tenir en un seul mot. Vous avez perdu !\n",
+		},
+		ruleTexts: map[string]string{
+			textgame.RuleLetter:    "aucun mot contenant la lettre '%s'",
+			textgame.RuleMaxWords:  "au plus %s mots par description",
+			textgame.RuleMaxLength: "aucun mot de plus de %s lettres",
+			textgame.RuleOneWord:   "un seul mot par indice",
+		},
+		rulesAre:       "Les règles sont : %s\n",
+		describerTurn:  "%s fait deviner. Les autres, ne regardez pas !\n",
+		hideCard:       "\nAppuyez sur Entrée pour cacher la carte et commencer.",
+		giveClue:       "\n%s, donnez un indice.\n> ",
+		playerGuess:    "%s, votre proposition : ",
+		playerGuessed:  "\n%s a deviné le mot ! Un point pour %s et pour %s.\n",
+		nobodyGuessed:  "\nPersonne n'a deviné. Le mot était %s.\n",
+		playerScore:    "  %s : %d\n",
+		hotSeatSummary: "\n=== Scores finaux ===\n",
 	},
 }
 
-const modelName = "gemini-2.5-flash-lite"
+const modelName = textgame.DefaultModel
 
 var client *genai.Client
 
+var judge *textgame.Judge
+
+var rounds = flag.Int("rounds", 3, "number of rounds in the match, or in hot-seat mode the number of cards each player describes")
+
+var players = flag.String("players", "", "comma-separated names of two or more players, for the hot-seat mode where the AI is only the judge")
+
+var (
+	tags       = flag.String("tags", "", "comma-separated categories of the cards, e.g. food,animals")
+	difficulty = flag.String("difficulty", "", "comma-separated difficulty levels of the cards: easy, medium, hard")
+	seed       = flag.Int64("seed", 0, "random seed of the card draws, to replay the same session (default random)")
+)
+
+var (
+	langFlag   = flag.String("lang", "", "language of the game: en or fr (default asked interactively)")
+	cardIDs    = flag.String("card", "", "comma-separated IDs of the cards to play, instead of random cards")
+	script     = flag.String("script", "", "file of the descriptions to play, one per line, instead of the standard input")
+	jsonOutput = flag.Bool("json", false, "print the verdicts, the guesses and the outcomes as JSON lines")
+)
+
+var native = flag.String("native", "", "native language of the player, for the cross-lingual mode: the cards are shown in this language, and described in -lang")
+
+var debrief = flag.Bool("debrief", false, "after each round, ask the AI to explain its guesses and give a tip")
+
+var rulesFlag = flag.String("rules", "", "comma-separated extra rules of the descriptions: letter:e (no word containing e), maxwords:N, maxlength:N (no longer words), oneword")
+
+// gameRules are the extra rules of the -rules flag.
+var gameRules []textgame.Rule
+
+var candidates = flag.Int("candidates", 0, "number of ranked candidate words of the AI guesser, with their confidence, instead of a single word")
+
 func main() {
+	flag.Parse()
 	ctx := context.Background()
 
 	//
@@ -92,6 +214,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	judge = &textgame.Judge{Client: client, Model: modelName}
 	if client.ClientConfig().Backend == genai.BackendVertexAI {
 		// fmt.Println("(using VertexAI backend)")
 	} else {
@@ -99,342 +222,446 @@ func main() {
 	}
 
 	// Load words from JSON file
-	file, err := os.ReadFile("assets/words.json")
+	allWords, err := deck.Load("assets/words.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	filter, err := deck.ParseFilter(*tags, *difficulty)
 	if err != nil {
-		log.Fatalf("failed to read words file: %v", err)
+		log.Fatal(err)
 	}
-
-	var allWords wordsByLang
-	if err := json.Unmarshal(file, &allWords); err != nil {
-		log.Fatalf("failed to parse words file: %v", err)
+	allWords = allWords.Filter(filter)
+	gameRules, err = textgame.ParseRules(*rulesFlag)
+	if err != nil {
+		log.Fatal(err)
 	}
 
+	if *jsonOutput {
+		out = io.Discard
+	}
 	reader := bufio.NewReader(os.Stdin)
+	if *script != "" {
+		if *langFlag == "" {
+			log.Fatal("-script needs -lang")
+		}
+		reader, err = openScript(*script)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	var words []forbiddenWord
-	var instructions string
-	var currentPhrases uiPhrases
-	var langName string
-
-	var langChosen = false
-	for !langChosen {
-		fmt.Print(phrases["en"].chooseLanguage)
-		lang, _ := reader.ReadString('\n')
-		lang = strings.TrimSpace(lang)
-
-		switch lang {
-		case "fr":
-			langChosen = true
-			words = allWords.Fr
-			currentPhrases = phrases["fr"]
-			instructions = `
-				Tu es le devineur dans une partie de "Mots Prohibés".
-				Je vais te décrire un mot. Tu dois deviner ce que c'est.
-				Tu n'as que 3 essais.
-				Je connais le mot à faire deviner, mais je ne peux pas te le dire.
-				Je ne peux pas non plus te dire plusieurs mots prohibés.
-				Réponds uniquement en Français.
-				Réponds uniquement le mot que tu supposes être celui que j'essaie de faire deviner.
-				Commençons.
-				`
-			langName = "French"
-		case "en":
-			langChosen = true
-			words = allWords.En
-			currentPhrases = phrases["en"]
-			instructions = `
-				You are the guesser in a game of "Proscribed Words".
-				I will describe a word to you. You have to guess what it is.
-				You only have 3 guesses.
-				I know the word to guess, but I cannot say it to you.
-				I also cannot say several other proscribed words.
-				Answer only in English.
-				Answer only with the word you think is the one I'm trying to let you guess.
-				Let's start.
-				`
-			langName = "English"
+	lang := *langFlag
+	if _, ok := phrases[lang]; lang != "" && !ok {
+		log.Fatalf("unsupported language %q", lang)
+	}
+	for {
+		if _, ok := phrases[lang]; ok {
+			break
+		}
+		fmt.Fprint(out, phrases["en"].chooseLanguage)
+		lang, err = readLine(reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	currentPhrases := phrases[lang]
+	langName := textgame.LanguageNames[lang]
+	if *native != "" {
+		if _, ok := phrases[*native]; !ok {
+			log.Fatalf("unsupported native language %q", *native)
+		}
+		if *native == lang {
+			log.Fatal("-native must be another language than the language of the game")
+		}
+		// The player reads in their native language
+		currentPhrases = phrases[*native]
+	}
 
+	// Pick distinct random words, one per round
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(*seed))
+	hotSeat := parsePlayers(*players)
+	if len(hotSeat) == 1 {
+		log.Fatal("the hot-seat mode needs at least two players")
+	}
+	nbRounds := *rounds
+	if len(hotSeat) >= 2 {
+		// Each player describes in turn
+		nbRounds *= len(hotSeat)
+	}
+	var cards []deck.Card
+	if *cardIDs != "" {
+		cards, err = pickCards(allWords, lang, *cardIDs)
+	} else {
+		cards, err = allWords.Draw(lang, nbRounds, r)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(hotSeat) >= 2 {
+		if *jsonOutput || *native != "" || *script != "" {
+			log.Fatal("-json, -native and -script are not supported in hot-seat mode")
+		}
+		gameWords := make([]textgame.Card, len(cards))
+		for i, card := range cards {
+			gameWords[i] = textgame.Card{Card: card, LanguageName: langName}
+		}
+		if err := playHotSeat(ctx, reader, hotSeat, gameWords, currentPhrases); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(out, "(-seed=%d)\n", *seed)
+		return
+	}
+
+	score := 0
+	results := make([]bool, len(cards))
+	considered := make([][]textgame.Candidate, len(cards))
+	var descriptions []string
+	for i, card := range cards {
+		fmt.Fprintf(out, currentPhrases.roundHeader, i+1, len(cards))
+		gameWord := textgame.Card{Card: card, LanguageName: langName}
+		shown := card
+		if *native != "" {
+			nativeCards, err := pickCards(allWords, *native, card.ID)
+			if err != nil {
+				log.Fatal(err)
+			}
+			shown = nativeCards[0]
+			gameWord = textgame.CrossLingualCard(card, shown, langName)
+		}
+		var res roundOutcome
+		res, err = playRound(ctx, reader, i+1, &gameWord, shown, lang, currentPhrases)
+		results[i], considered[i] = res.won, res.considered
+		descriptions = append(descriptions, res.descriptions...)
+		if results[i] {
+			score++
+		}
+		if err == io.EOF {
+			// No more descriptions
+			cards = cards[:i+1]
+			break
+		} else if err != nil {
+			log.Fatal(err)
+		}
+	}
+	emit(event{Type: "match", Score: &score, Rounds: len(cards), Seed: *seed})
+
+	fmt.Fprintf(out, currentPhrases.matchSummary, score, len(cards))
+	for i, card := range cards {
+		if results[i] {
+			fmt.Fprintf(out, currentPhrases.roundWon, i+1, card.Word)
+		} else {
+			fmt.Fprintf(out, currentPhrases.roundLost, i+1, card.Word)
+		}
+		if len(considered[i]) > 0 {
+			fmt.Fprintf(out, currentPhrases.alsoConsidered, formatCandidates(considered[i]))
 		}
 	}
-	//fmt.Println(instructions)
+	if *native != "" && len(descriptions) > 0 {
+		printLanguageFeedback(ctx, langName, textgame.LanguageNames[*native], descriptions, currentPhrases)
+	}
+	fmt.Fprintf(out, "(-seed=%d)\n", *seed)
+}
 
-	// Pick a random word
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	gameWord := words[r.Intn(len(words))]
-	gameWord.languageName = langName
+// roundOutcome is what happened in a round of the CLI.
+type roundOutcome struct {
+	// won means the AI found the word.
+	won bool
+	// considered are the ranked candidates which the AI didn't guess.
+	considered []textgame.Candidate
+	// descriptions are those of the human, in order.
+	descriptions []string
+}
+
+// playRound lets the human describe gameWord to a fresh AI guesser, showing
+// them the shown card, which is gameWord except in the cross-lingual mode.
+// It returns io.EOF if the descriptions ran out before the end of the round.
+func playRound(ctx context.Context, reader *bufio.Reader, round int, gameWord *textgame.Card, shown deck.Card, lang string, currentPhrases uiPhrases) (res roundOutcome, err error) {
+	reason := reasonNoMoreGuesses
+	var transcript []textgame.Line
+	var considered []textgame.Candidate
+	var said string // the proscribed word which lost the round
+	var violation *textgame.Violation
+	defer func() {
+		res.considered = considered
+		for _, line := range transcript {
+			if line.Speaker == "human" {
+				res.descriptions = append(res.descriptions, line.Text)
+			}
+		}
+		emit(event{Type: "round", Round: round, Card: gameWord.ID, Word: gameWord.Word, Won: &res.won, Reason: reason})
+		if *debrief && reason != reasonEndOfInput {
+			printDebrief(ctx, round, gameWord, transcript, outcome(reason, said, violation), currentPhrases)
+		}
+	}()
 
-	fmt.Println()
-	fmt.Printf(currentPhrases.wordToDescribe, gameWord.Word)
-	fmt.Printf(currentPhrases.forbiddenWordsAre, strings.Join(gameWord.Forbidden, ", "))
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, currentPhrases.wordToDescribe, shown.Word)
+	fmt.Fprintf(out, currentPhrases.forbiddenWordsAre, strings.Join(shown.Forbidden, ", "))
+	printRules(currentPhrases)
 
 	var config *genai.GenerateContentConfig = &genai.GenerateContentConfig{
 		SystemInstruction: &genai.Content{
 			Parts: []*genai.Part{
-				{Text: instructions},
+				{Text: textgame.GuesserInstructions[lang]},
 			},
 		},
 	}
-
-	chat, err := client.Chats.Create(ctx, modelName, config, nil)
-	if err != nil {
-		log.Fatal(err)
+	ranked := *candidates > 0
+	if ranked {
+		config = textgame.RankedGuessConfig(lang, *candidates)
 	}
 
+	// The conversation with the guesser. A description which used a
+	// proscribed word never makes it to the history.
+	var history []*genai.Content
+
 	guesses := 3
 	for guesses > 0 {
-		fmt.Println(currentPhrases.describeTheWord)
-		description, _ := reader.ReadString('\n')
-		description = strings.TrimSpace(description)
+		fmt.Fprintln(out, currentPhrases.describeTheWord)
+		description, err := readLine(reader)
+		if err != nil {
+			reason = reasonEndOfInput
+			return res, err
+		}
+		transcript = append(transcript, textgame.Line{Speaker: "human", Text: description})
+
+		// The rules are checked locally, before asking the AI
+		if violation = textgame.CheckRules(gameRules, description); violation != nil {
+			emit(event{Type: "verdict", Round: round, Card: gameWord.ID, Description: description, Violation: violation})
+			printRuleViolation(violation, currentPhrases)
+			reason = reasonRule
+			return res, nil
+		}
 
-		g := new(errgroup.Group)
+		g, gctx := errgroup.WithContext(ctx)
+		guessCtx, cancelGuess := context.WithCancel(gctx)
+		var printer streamPrinter
+		fmt.Fprint(out, currentPhrases.aiGuess)
+		printer.startSpinner()
 
 		// Check for proscribed words
-		var lost bool
-		var forbiddenSaid, forbiddenMatched string
+		var verdict textgame.Verdict
 		g.Go(func() error {
-			lost, forbiddenSaid, forbiddenMatched, err = gameWord.saidForbidden(ctx, description)
-			return err
+			defer printer.stopSpinner()
+			v, err := judge.SaidForbidden(gctx, gameWord, description)
+			if err != nil {
+				return err
+			}
+			verdict = v
+			if v.Lost {
+				// No need for a guess
+				cancelGuess()
+			}
+			return nil
 		})
 
-		// Let Gemini guess, concurrently
-		var result *genai.GenerateContentResponse
+		// Let Gemini guess, concurrently, and show the guess as it streams in
+		userTurn := genai.NewContentFromText(description, genai.RoleUser)
+		contents := append(slices.Clip(history), userTurn)
+		var guess strings.Builder
 		g.Go(func() error {
-			result, err = chat.SendMessage(ctx, genai.Part{Text: description})
-			return err
+			for chunk, err := range textgame.GenerateStream(guessCtx, client, modelName, contents, config) {
+				if err != nil {
+					if guessCtx.Err() != nil && ctx.Err() == nil {
+						// Canceled by the verdict, or by the failure of the judge
+						return nil
+					}
+					return err
+				}
+				text, _ := textgame.TextOf(chunk) // checked by GenerateStream
+				guess.WriteString(text)
+				if !ranked {
+					printer.write(text)
+				}
+			}
+			return nil
 		})
 
-		err := g.Wait()
+		err = g.Wait()
+		cancelGuess()
+		var ranking []textgame.Candidate
+		if err == nil && ranked && !verdict.Lost {
+			ranking, err = textgame.ParseRankedGuess(guess.String())
+			if err == nil {
+				fmt.Fprintf(out, "%s (%.0f%%)", ranking[0].Word, 100*ranking[0].Confidence)
+			}
+		}
+		fmt.Fprintln(out)
 		if err != nil {
-			log.Fatal(err)
+			// This turn doesn't count
+			emit(event{Type: "error", Round: round, Card: gameWord.ID, Description: description, Error: err.Error()})
+			fmt.Fprintf(out, currentPhrases.modelError, err)
+			continue
 		}
 
-		if lost {
-			if normalize(forbiddenSaid) == normalize(forbiddenMatched) {
-				// Exact match
-				fmt.Printf(currentPhrases.usedForbiddenWord, forbiddenMatched)
-			} else {
-				// Fuzzy match
-				fmt.Printf(currentPhrases.usedForbiddenInflection, forbiddenSaid, forbiddenMatched)
-			}
-			return
+		emit(event{Type: "verdict", Round: round, Card: gameWord.ID, Description: description, Verdict: &verdict})
+		printJudgeNotes(verdict)
+		if verdict.Lost {
+			printViolation(verdict, currentPhrases)
+			reason = reasonForbidden
+			said = verdict.Fragment
+			return res, nil
 		}
 
 		// AI's guess
-		aiResponse := textOf(result)
-		fmt.Printf(currentPhrases.aiGuess, aiResponse)
-
-		winning, err := gameWord.isWinning(ctx, aiResponse)
-		if err != nil {
-			log.Fatal(err)
+		aiResponse := guess.String()
+		history = append(history, userTurn, genai.NewContentFromText(aiResponse, genai.RoleModel))
+		if ranked {
+			// Only the top candidate counts
+			aiResponse = ranking[0].Word
+			considered = addCandidates(considered, ranking[1:])
 		}
-
-		if winning {
-			fmt.Println(currentPhrases.aiGuessedTheWord)
-			return
+		emit(event{Type: "guess", Round: round, Card: gameWord.ID, Description: description, Guess: aiResponse, Candidates: ranking})
+		transcript = append(transcript, textgame.Line{Speaker: "ai", Text: aiResponse})
+
+		if gameWord.IsWinning(aiResponse) {
+			fmt.Fprintln(out, currentPhrases.aiGuessedTheWord)
+			reason = reasonGuessed
+			res.won = true
+			return res, nil
 		}
 		guesses--
 	}
 
-	fmt.Printf(currentPhrases.wordWas, gameWord.Word)
-}
-
-func (fw *forbiddenWord) isWinning(ctx context.Context, guess string) (bool, error) {
-	lowGuess := normalize(guess)
-	lowGoal := normalize(fw.Word)
-	return strings.Contains(lowGuess, lowGoal), nil
+	fmt.Fprintf(out, currentPhrases.wordWas, gameWord.Word)
+	return res, nil
 }
 
-// normalize returns its argument lowercased and without diacritics
-func normalize(s string) string {
-	// Local transformers, not shared with other goroutines
-	tr := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
-	normalized, _, err := transform.String(tr, strings.ToLower(s))
-	if err != nil {
-		// We do not expect string transformation to fail in general
-		panic(err)
+// addCandidates adds the new candidates to the list, unless they're already
+// in it.
+func addCandidates(list, candidates []textgame.Candidate) []textgame.Candidate {
+	for _, c := range candidates {
+		if !slices.ContainsFunc(list, func(l textgame.Candidate) bool {
+			return textgame.Normalize(l.Word) == textgame.Normalize(c.Word)
+		}) {
+			list = append(list, c)
+		}
 	}
-	return normalized
+	return list
 }
 
-func (fw *forbiddenWord) saidForbidden(ctx context.Context, said string) (lost bool, forbiddenSaid string, forbiddenMatched string, err error) {
-	systemInstruction := `
-		You are the judge in the Proscribed Words game.
-		The human player will say a description.
-
-		If the prompt contains any of the proscribed words, or an inflection of a forbidden
-		word, or a proscribed word translated in another language, then the game is lost.
-
-		The proscribed words are:
-		` + fw.Word + ", " + strings.Join(fw.Forbidden, ", ") + `
-
-		In the field "forbiddenWord", provide exactly one of the original proscribed words.
-
-		In the field "fragment", provide the part of the prompt that violated the rule.
-
-		The description must be rejected as using a proscribed word only if it actually contains
-		an inflection, or misspelling, or translation of a proscribed word.
-
-		Synonyms of proscribed words must not trigger a lost game.
-
-		E.g. "ficelle" does not match the proscribed word "Corde", because the two words have
-		a similar meaning but the word "ficelle" is not an inflection of the word "corde" and
-		the game is not lost.
-
-		E.g. "orange" does not match the proscribed word "Agrume", because the two words have
-		a similar meaning but the word "orange" is not an inflection of the word "Agrume" and
-		the game is not lost.
-
-		E.g. "tronc" does not match the proscribed word "Arbre", because the two words have
-		related meaning but the word "tronc" is not an inflection of the word "Arbre" and
-		the game is not lost.
-
-		E.g. "poussent" matches the proscribed word "Pousser", because "poussent" is a
-		conjugation of the verb "Pousser", thus it is an inflection of "Pousser" and the game
-		is lost.
-`
-
-	// Force JSON structured output
-	config := &genai.GenerateContentConfig{
-		SystemInstruction: genai.NewContentFromParts([]*genai.Part{
-			{Text: systemInstruction},
-		}, genai.RoleModel),
-		ResponseMIMEType: "application/json",
-		ResponseJsonSchema: &genai.Schema{
-			Type: genai.TypeObject,
-			Properties: map[string]*genai.Schema{
-				"lost": {
-					Type:        genai.TypeBoolean,
-					Description: "Indicates if the user has lost the game.",
-				},
-				"forbiddenWord": {
-					Type:        genai.TypeString,
-					Description: "The word that triggered the loss condition.",
-				},
-				"fragment": {
-					Type:        genai.TypeString,
-					Description: "The text fragment analyzed.",
-				},
-			},
-			Required: []string{"lost"},
-		},
-	}
-
-	prompt := []*genai.Content{
-		genai.NewContentFromParts([]*genai.Part{
-			{Text: said},
-		}, genai.RoleUser),
+// formatCandidates lists the words with their confidence, e.g. "bee (20%), wasp (5%)".
+func formatCandidates(candidates []textgame.Candidate) string {
+	items := make([]string, len(candidates))
+	for i, c := range candidates {
+		items[i] = fmt.Sprintf("%s (%.0f%%)", c.Word, 100*c.Confidence)
 	}
+	return strings.Join(items, ", ")
+}
 
-	resp, err := client.Models.GenerateContent(ctx, modelName, prompt, config)
-
-	if err != nil {
-		return false, "", "", err
+// printViolation tells which proscribed word was said.
+func printViolation(v textgame.Verdict, currentPhrases uiPhrases) {
+	forbiddenSaid, forbiddenMatched := v.Fragment, v.ForbiddenWord
+	if textgame.Normalize(forbiddenSaid) == textgame.Normalize(forbiddenMatched) {
+		// Exact match
+		fmt.Fprintf(out, currentPhrases.usedForbiddenWord, forbiddenMatched)
+	} else {
+		// Fuzzy match
+		fmt.Fprintf(out, currentPhrases.usedForbiddenInflection, forbiddenSaid, forbiddenMatched)
 	}
+}
 
-	structureAnswer := resp.Candidates[0].Content.Parts[0].Text
-
-	// Parse structureAnswer to return the fields
-	var result struct {
-		Lost          bool   `json:"lost"`
-		ForbiddenWord string `json:"forbiddenWord"`
-		Fragment      string `json:"fragment"`
+// printJudgeNotes explains the verdict of the judge, if anything looked like
+// a proscribed word.
+func printJudgeNotes(v textgame.Verdict) {
+	if v.Suspicious() {
+		// False alarm
+		fmt.Fprintf(out, "\nJudge says: the words '%s' and '%s' looked suspiciously similar (%s, %.0f%%), but not enough\n", v.Fragment, v.ForbiddenWord, v.Reason, 100*v.Confidence)
 	}
-	if err := json.Unmarshal([]byte(structureAnswer), &result); err != nil {
-		return false, "", "", fmt.Errorf("failed to parse AI response: %w", err)
+	if !v.Lost {
+		return
 	}
 
-	if !result.Lost {
-		return false, "", "", nil
-	}
-
-	// Sometimes words are incorrectly detected as proscribed, just because they are
-	// semantically close to one of the proscribed words.
-	// E.g. " 'nuages' est trop proche du mot prohibé 'Ciel' "
-	//
-	// Let's double-check if the suspicious fragment is actually either an inflection,
-	// or a translation, of the proscribed word.
-	var isSameRoot, isTranslated bool
-
-	g := new(errgroup.Group)
-	g.Go(func() error {
-		isSameRoot, err = haveSameRoot(ctx, result.Fragment, result.ForbiddenWord)
-		return err
-	})
-	g.Go(func() error {
-		isTranslated, err = isTranslation(ctx, result.Fragment, result.ForbiddenWord, fw.languageName)
-		return err
-	})
-	err = g.Wait()
-	if err != nil {
-		return false, "", "", err
+	switch v.Reason {
+	case textgame.ReasonInflection:
+		fmt.Fprintf(out, "\nJudge says: the words '%s' and '%s' have the same root\n", v.Fragment, v.ForbiddenWord)
+	case textgame.ReasonMisspelling:
+		fmt.Fprintf(out, "\nJudge says: '%s' is a misspelling of the proscribed word '%s'\n", v.Fragment, v.ForbiddenWord)
+	case textgame.ReasonTranslation:
+		fmt.Fprintf(out, "\nJudge says: '%s' is a translation of the proscribed word '%s'\n", v.Fragment, v.ForbiddenWord)
 	}
+}
 
-	if !isSameRoot && !isTranslated {
-		// False alarm
-		fmt.Printf("\nJudge says: the words '%s' and '%s' looked suspiciously similar, but not for sure\n", result.Fragment, result.ForbiddenWord)
-		return false, "", "", nil
+// printRules lists the extra rules, if any.
+func printRules(currentPhrases uiPhrases) {
+	if len(gameRules) == 0 {
+		return
 	}
-
-	if isSameRoot {
-		fmt.Printf("\nJudge says: the words '%s' and '%s' have the same root\n", result.Fragment, result.ForbiddenWord)
+	texts := make([]string, len(gameRules))
+	for i, r := range gameRules {
+		name, limit, _ := strings.Cut(r.String(), ":")
+		texts[i] = currentPhrases.ruleTexts[name]
+		if limit != "" {
+			texts[i] = fmt.Sprintf(texts[i], limit)
+		}
 	}
+	fmt.Fprintf(out, currentPhrases.rulesAre, strings.Join(texts, ", "))
+}
 
-	if isTranslated {
-		fmt.Printf("\nJudge says: '%s' is a translation of the proscribed word '%s'\n", result.Fragment, result.ForbiddenWord)
+// printRuleViolation tells which rule was broken.
+func printRuleViolation(v *textgame.Violation, currentPhrases uiPhrases) {
+	if v.Rule == textgame.RuleOneWord {
+		fmt.Fprintf(out, currentPhrases.brokeRule[v.Rule], v.Fragment)
+		return
 	}
-
-	return result.Lost, result.Fragment, result.ForbiddenWord, nil
+	fmt.Fprintf(out, currentPhrases.brokeRule[v.Rule], v.Fragment, v.Limit)
 }
 
-func haveSameRoot(ctx context.Context, word1, word2 string) (bool, error) {
-	// TODO stemmer e.g. PorterStemmer
-	prompt := []*genai.Content{
-		genai.NewContentFromParts([]*genai.Part{
-			{Text: fmt.Sprintf(
-				`Can we say that the words '%s' and '%s' share the same root?
-				 Answer just Yes or No, and nothing else.`, word1, word2)},
-		}, genai.RoleUser),
+// outcome describes the end of a round to the model, for its debrief.
+func outcome(reason, said string, violation *textgame.Violation) string {
+	switch reason {
+	case reasonRule:
+		return fmt.Sprintf("lost: the describer said %q, which broke the rule %s", violation.Fragment, violation.Rule)
+	case reasonGuessed:
+		return "won: the guesser found the word"
+	case reasonForbidden:
+		return fmt.Sprintf("lost: the describer said %q, too close to a proscribed word", said)
+	default:
+		return "lost: the guesser didn't find the word in 3 guesses"
 	}
+}
 
-	resp, err := client.Models.GenerateContent(ctx, modelName, prompt, nil)
-
+// printDebrief asks the AI to explain the round, and prints its explanation.
+func printDebrief(ctx context.Context, round int, gameWord *textgame.Card, transcript []textgame.Line, outcome string, currentPhrases uiPhrases) {
+	d, err := textgame.DebriefRound(ctx, client, modelName, textgame.RoundReport{
+		Card:       gameWord,
+		Transcript: transcript,
+		Outcome:    outcome,
+	})
 	if err != nil {
-		return false, err
+		emit(event{Type: "error", Round: round, Card: gameWord.ID, Error: err.Error()})
+		fmt.Fprintf(out, currentPhrases.debriefFailed, err)
+		return
 	}
-
-	answer := strings.ToLower(resp.Candidates[0].Content.Parts[0].Text)
-
-	return answer == "yes", nil
-}
-
-func isTranslation(ctx context.Context, word1, word2 string, word2Lang string) (bool, error) {
-	prompt := []*genai.Content{
-		genai.NewContentFromParts([]*genai.Part{
-			{Text: fmt.Sprintf(
-				`Can we say that the word '%s' is a translation of the %s word '%s' in another language?
-				 Answer just Yes or No, and nothing else.`, word1, word2Lang, word2)},
-		}, genai.RoleUser),
+	emit(event{Type: "debrief", Round: round, Card: gameWord.ID, Debrief: &d})
+	fmt.Fprint(out, currentPhrases.debriefHeader)
+	fmt.Fprintln(out, d.Explanation)
+	if len(d.HelpfulPhrases) > 0 {
+		fmt.Fprintf(out, currentPhrases.helpfulPhrases, `"`+strings.Join(d.HelpfulPhrases, `", "`)+`"`)
 	}
+	fmt.Fprintf(out, currentPhrases.tip, d.Tip)
+}
 
-	resp, err := client.Models.GenerateContent(ctx, modelName, prompt, nil)
-
+// printLanguageFeedback asks the AI for vocabulary and grammar feedback on
+// the descriptions of the cross-lingual mode, and prints it.
+func printLanguageFeedback(ctx context.Context, learningLanguage, nativeLanguage string, descriptions []string, currentPhrases uiPhrases) {
+	f, err := textgame.FeedbackOnLanguage(ctx, client, modelName, learningLanguage, nativeLanguage, descriptions)
 	if err != nil {
-		return false, err
+		emit(event{Type: "error", Error: err.Error()})
+		fmt.Fprintf(out, currentPhrases.feedbackFailed, err)
+		return
 	}
-
-	answer := resp.Candidates[0].Content.Parts[0].Text
-
-	return strings.ToLower(answer) == "yes", nil
-}
-
-func checkNotEmpty(res *genai.GenerateContentResponse) {
-	if len(res.Candidates) == 0 ||
-		len(res.Candidates[0].Content.Parts) == 0 {
-		log.Fatalf("empty response from model")
+	emit(event{Type: "feedback", Feedback: &f})
+	fmt.Fprint(out, currentPhrases.feedbackHeader)
+	fmt.Fprintln(out, f.Summary)
+	for _, c := range f.Corrections {
+		fmt.Fprintf(out, currentPhrases.correction, c.Original, c.Corrected, c.Explanation)
+	}
+	if len(f.Vocabulary) > 0 {
+		fmt.Fprintf(out, currentPhrases.vocabulary, strings.Join(f.Vocabulary, ", "))
 	}
-}
-
-func textOf(res *genai.GenerateContentResponse) string {
-	checkNotEmpty(res)
-	return res.Candidates[0].Content.Parts[0].Text
 }
@@ -0,0 +1,260 @@
+// Command deckgen asks the model to write new cards, or to translate the
+// existing cards into another language. The cards go to a review file, which
+// is merged into the deck only after a human has checked it.
+//
+// New cards, with their IDs derived from the words, or given as id=word:
+//
+//	go run ./cmd/deckgen -lang en kite "ice-cream=Ice Cream"
+//
+// Translation of the English cards into French, keeping their IDs:
+//
+//	go run ./cmd/deckgen -lang fr -translate-from en
+//
+// Merge of the reviewed file into the deck:
+//
+//	go run ./cmd/deckgen -merge deck_review.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/Deleplace/verboten/deck"
+	"github.com/Deleplace/verboten/textgame"
+)
+
+var (
+	wordsFile     = flag.String("words", "assets/words.json", "deck file")
+	wordsList     = flag.String("words-list", "", "file of the words of the new cards, one per line (in addition to the arguments)")
+	lang          = flag.String("lang", "", "language of the cards to write")
+	translateFrom = flag.String("translate-from", "", "translate the cards of this language which are missing in -lang")
+	ids           = flag.String("ids", "", "comma-separated IDs of the cards to translate (default all the missing ones)")
+	forbidden     = flag.Int("forbidden", 4, "number of forbidden words per card")
+	out           = flag.String("out", "deck_review.json", "review file to write")
+	merge         = flag.String("merge", "", "merge this reviewed file into the deck, instead of writing cards")
+	model         = flag.String("model", textgame.DefaultModel, "model writing the cards")
+	baseURL       = flag.String("base-url", "", "Gemini API base URL, e.g. the local stand-in http://localhost:8081/")
+)
+
+var client *genai.Client
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+	ctx := context.Background()
+
+	words, err := deck.Load(*wordsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *merge != "" {
+		mergeReview(words, *merge)
+		return
+	}
+
+	if *lang == "" {
+		log.Fatal("missing -lang")
+	}
+	client, err = textgame.NewClient(ctx, *baseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var cards []deck.Card
+	if *translateFrom != "" {
+		cards, err = translateCards(ctx, words)
+	} else {
+		cards, err = newCards(ctx)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(cards) == 0 {
+		log.Fatal("no cards to write")
+	}
+
+	review := deck.Deck{*lang: cards}
+	if err := review.Save(*out); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote %d cards to %s", len(cards), *out)
+	for _, p := range review.Lint() {
+		log.Printf("warning: %s", p)
+	}
+	log.Printf("Review and edit them, then run: go run ./cmd/deckgen -merge %s", *out)
+}
+
+// newCards writes a card for each word of the arguments and of -words-list.
+func newCards(ctx context.Context) ([]deck.Card, error) {
+	targets := flag.Args()
+	if *wordsList != "" {
+		data, err := os.ReadFile(*wordsList)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				targets = append(targets, line)
+			}
+		}
+	}
+
+	var cards []deck.Card
+	for _, target := range targets {
+		id, word, ok := strings.Cut(target, "=")
+		if !ok {
+			id, word = slug(target), target
+		}
+		prompt := fmt.Sprintf(`
+			You write the cards of the game "Proscribed Words", in %s.
+			The describer of a card must let the other players guess the word, without saying
+			the word itself nor any of its forbidden words.
+			Write the card of the word "%s".
+			The forbidden words are the %d words which players would most naturally use to
+			describe it, so that the card is a fun challenge.
+			Each forbidden word is a single common word, capitalized like a title.
+			Don't use the word itself, nor its inflections, as a forbidden word.
+		`, languageName(*lang), word, *forbidden)
+		card, err := generateCard(ctx, prompt)
+		if err != nil {
+			return cards, fmt.Errorf("card %q: %w", word, err)
+		}
+		card.ID = id
+		log.Printf("%s: %s %v", card.ID, card.Word, card.Forbidden)
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// translateCards translates the cards of -translate-from which are missing
+// in -lang, or the cards of -ids.
+func translateCards(ctx context.Context, words deck.Deck) ([]deck.Card, error) {
+	existing := make(map[string]bool)
+	for _, c := range words[*lang] {
+		existing[c.ID] = true
+	}
+	wanted := make(map[string]bool)
+	if *ids != "" {
+		for _, id := range strings.Split(*ids, ",") {
+			wanted[strings.TrimSpace(id)] = true
+		}
+	}
+
+	var cards []deck.Card
+	for _, src := range words[*translateFrom] {
+		if len(wanted) > 0 && !wanted[src.ID] || len(wanted) == 0 && existing[src.ID] {
+			continue
+		}
+		prompt := fmt.Sprintf(`
+			You translate the cards of the game "Proscribed Words" from %s to %s.
+			The describer of a card must let the other players guess the word, without saying
+			the word itself nor any of its forbidden words.
+			The card to translate is the word "%s", with the forbidden words: %s.
+			Give the most common translation of the word, capitalized like a title.
+			Give %d forbidden words in %s: translate the original ones when their
+			translation is natural, and otherwise replace them by the words which players
+			would most naturally use to describe the translated word.
+			Don't use the translated word itself, nor its inflections, as a forbidden word.
+		`, languageName(*translateFrom), languageName(*lang), src.Word, strings.Join(src.Forbidden, ", "),
+			*forbidden, languageName(*lang))
+		card, err := generateCard(ctx, prompt)
+		if err != nil {
+			return cards, fmt.Errorf("card %q: %w", src.ID, err)
+		}
+		card.ID = src.ID
+		log.Printf("%s: %s %v", card.ID, card.Word, card.Forbidden)
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// generateCard asks the model for the word and the forbidden words of a card.
+func generateCard(ctx context.Context, prompt string) (deck.Card, error) {
+	// Force JSON structured output
+	count := int64(*forbidden)
+	config := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseJsonSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"word": {
+					Type:        genai.TypeString,
+					Description: "The word to guess.",
+				},
+				"forbidden": {
+					Type:        genai.TypeArray,
+					Description: "The forbidden words.",
+					Items:       &genai.Schema{Type: genai.TypeString},
+					MinItems:    &count,
+					MaxItems:    &count,
+				},
+			},
+			Required: []string{"word", "forbidden"},
+		},
+	}
+
+	resp, err := textgame.Generate(ctx, client, *model, genai.Text(prompt), config)
+	if err != nil {
+		return deck.Card{}, err
+	}
+	text, err := textgame.TextOf(resp)
+	if err != nil {
+		return deck.Card{}, err
+	}
+
+	var card deck.Card
+	if err := json.Unmarshal([]byte(text), &card); err != nil {
+		return deck.Card{}, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	return card, nil
+}
+
+// mergeReview adds the cards of the review file to the deck, and saves the deck.
+func mergeReview(words deck.Deck, reviewFile string) {
+	review, err := deck.Load(reviewFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if problems := review.Lint(); len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		log.Fatalf("%s has %d problems, fix them before merging", reviewFile, len(problems))
+	}
+
+	words.Merge(review)
+	if err := words.Save(*wordsFile); err != nil {
+		log.Fatal(err)
+	}
+	for lang, cards := range review {
+		log.Printf("Merged %d %s cards into %s", len(cards), lang, *wordsFile)
+	}
+	// e.g. the other languages don't have the new cards yet
+	for _, p := range words.Lint() {
+		log.Printf("warning: %s", p)
+	}
+	log.Print("Don't forget the image of each new card in assets/words_img (see cmd/decklint)")
+}
+
+var nonAlnum = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug returns a card ID for the word, e.g. "Crème glacée" -> "creme-glacee".
+func slug(word string) string {
+	return strings.Trim(nonAlnum.ReplaceAllString(textgame.Normalize(word), "-"), "-")
+}
+
+func languageName(lang string) string {
+	if name := textgame.LanguageNames[lang]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("the language of code %q", lang)
+}
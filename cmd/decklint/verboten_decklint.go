@@ -0,0 +1,88 @@
+// Command decklint checks the deck and the word images, and exits with a
+// non-zero status if it finds any problem, so that deck edits are caught
+// before deploy.
+//
+//	go run ./cmd/decklint
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Deleplace/verboten/deck"
+)
+
+var (
+	wordsFile = flag.String("words", "assets/words.json", "deck file")
+	imagesDir = flag.String("images", "assets/words_img", "directory of the word images, named {id}.png")
+)
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+
+	words, err := deck.Load(*wordsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	problems := words.Lint()
+	problems = append(problems, lintImages(words, *imagesDir)...)
+
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		log.Printf("%d problems found", len(problems))
+		os.Exit(1)
+	}
+}
+
+// lintImages checks that each card has an image, and that each file of the
+// images directory is the image of a card.
+func lintImages(words deck.Deck, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	files := make(map[string]bool)
+	for _, e := range entries {
+		files[e.Name()] = true
+	}
+
+	var problems []string
+	used := make(map[string]bool)
+	for _, lang := range slices.Sorted(maps.Keys(words)) {
+		for _, c := range words[lang] {
+			if c.ID == "" {
+				continue
+			}
+			name := c.ID + ".png"
+			used[name] = true
+			if !files[name] {
+				problems = append(problems, fmt.Sprintf("%s/%s: missing image %s", lang, c.ID, filepath.Join(dir, name)))
+			}
+		}
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case e.IsDir():
+			problems = append(problems, fmt.Sprintf("%s: unexpected directory", filepath.Join(dir, name)))
+		case strings.HasPrefix(name, "."):
+			problems = append(problems, fmt.Sprintf("%s: stray hidden file", filepath.Join(dir, name)))
+		case !strings.HasSuffix(name, ".png"):
+			problems = append(problems, fmt.Sprintf("%s: not a .png image", filepath.Join(dir, name)))
+		case !used[name]:
+			problems = append(problems, fmt.Sprintf("%s: orphan image, no card has this id", filepath.Join(dir, name)))
+		}
+	}
+	return problems
+}
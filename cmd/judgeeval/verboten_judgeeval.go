@@ -0,0 +1,196 @@
+// Command judgeeval scores a judge on a labeled corpus of descriptions, to
+// compare prompts and models objectively.
+//
+// A positive case is a description which must lose the game. The report gives
+// the precision and recall of the judge, whether it matched the expected
+// proscribed word, and the accuracy per category of case.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Deleplace/verboten/deck"
+	"github.com/Deleplace/verboten/textgame"
+)
+
+var (
+	corpusFile  = flag.String("corpus", "cmd/judgeeval/corpus.json", "labeled corpus file")
+	judgeName   = flag.String("judge", "model", "judge implementation to evaluate: model, or local for the matcher without model calls")
+	model       = flag.String("model", textgame.DefaultModel, "model of the judge")
+	baseURL     = flag.String("base-url", "", "Gemini API base URL, e.g. the local stand-in http://localhost:8081/")
+	concurrency = flag.Int("concurrency", 4, "number of cases judged concurrently")
+	verbose     = flag.Bool("v", false, "print each wrong verdict")
+)
+
+// evalCase is a description of a card, labeled with the expected verdict.
+type evalCase struct {
+	Lang string `json:"lang"`
+	// Category is "exact", "inflection", "translation" or "synonym".
+	// Synonyms must not lose the game.
+	Category      string    `json:"category"`
+	Card          deck.Card `json:"card"`
+	Description   string    `json:"description"`
+	Lost          bool      `json:"lost"`
+	ForbiddenWord string    `json:"forbiddenWord,omitempty"`
+}
+
+type outcome struct {
+	verdict textgame.Verdict
+	err     error
+}
+
+// score counts the verdicts of one category, or of the whole corpus.
+type score struct {
+	cases, correct, errors int
+	// truePos etc. are about the expected and actual Lost.
+	truePos, falsePos, falseNeg int
+	// wordMatched counts the true positives which matched the expected proscribed word.
+	wordMatched int
+}
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+	ctx := context.Background()
+
+	data, err := os.ReadFile(*corpusFile)
+	if err != nil {
+		log.Fatalf("failed to read corpus: %v", err)
+	}
+	var cases []evalCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		log.Fatalf("failed to parse corpus: %v", err)
+	}
+
+	referee, err := newReferee(ctx, *judgeName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outcomes := make([]outcome, len(cases))
+	var g errgroup.Group
+	g.SetLimit(*concurrency)
+	for i, c := range cases {
+		g.Go(func() error {
+			card := &textgame.Card{Card: c.Card, LanguageName: textgame.LanguageNames[c.Lang]}
+			outcomes[i].verdict, outcomes[i].err = referee.SaidForbidden(ctx, card, c.Description)
+			return nil
+		})
+	}
+	g.Wait()
+
+	var total score
+	byCategory := make(map[string]*score)
+	for i, c := range cases {
+		if byCategory[c.Category] == nil {
+			byCategory[c.Category] = &score{}
+		}
+		ok := total.add(c, outcomes[i])
+		byCategory[c.Category].add(c, outcomes[i])
+		if !ok && *verbose {
+			report(c, outcomes[i])
+		}
+	}
+
+	fmt.Printf("judge %s, model %s, %d cases\n", *judgeName, *model, total.cases)
+	fmt.Printf("precision %.2f  recall %.2f  accuracy %.2f  matched word %.2f  errors %d\n\n",
+		total.precision(), total.recall(), total.accuracy(), total.wordAccuracy(), total.errors)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CATEGORY\tCASES\tCORRECT\tACCURACY\tERRORS")
+	categories := make([]string, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	slices.Sort(categories)
+	for _, category := range categories {
+		s := byCategory[category]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f\t%d\n", category, s.cases, s.correct, s.accuracy(), s.errors)
+	}
+	w.Flush()
+}
+
+// newReferee returns the judge implementation of the given name.
+func newReferee(ctx context.Context, name string) (textgame.Referee, error) {
+	switch name {
+	case "model":
+		client, err := textgame.NewClient(ctx, *baseURL)
+		if err != nil {
+			return nil, err
+		}
+		return &textgame.Judge{Client: client, Model: *model}, nil
+	case "local":
+		return textgame.LocalMatcher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown judge %q", name)
+	}
+}
+
+// add counts the outcome of c, and tells if the verdict was right.
+func (s *score) add(c evalCase, o outcome) bool {
+	s.cases++
+	if o.err != nil {
+		s.errors++
+		return false
+	}
+	lost := o.verdict.Lost
+	switch {
+	case c.Lost && lost:
+		s.truePos++
+		if strings.EqualFold(o.verdict.ForbiddenWord, c.ForbiddenWord) {
+			s.wordMatched++
+		}
+	case !c.Lost && lost:
+		s.falsePos++
+	case c.Lost && !lost:
+		s.falseNeg++
+	}
+	if lost == c.Lost {
+		s.correct++
+		return true
+	}
+	return false
+}
+
+func (s *score) precision() float64 {
+	return ratio(s.truePos, s.truePos+s.falsePos)
+}
+
+func (s *score) recall() float64 {
+	return ratio(s.truePos, s.truePos+s.falseNeg)
+}
+
+func (s *score) accuracy() float64 {
+	return ratio(s.correct, s.cases)
+}
+
+func (s *score) wordAccuracy() float64 {
+	return ratio(s.wordMatched, s.truePos)
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func report(c evalCase, o outcome) {
+	if o.err != nil {
+		fmt.Fprintf(os.Stderr, "[%s %s] %q: error: %v\n", c.Lang, c.Category, c.Description, o.err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[%s %s] %q: expected lost=%v (%s), got lost=%v (%q, %s, %s %.2f)\n",
+		c.Lang, c.Category, c.Description, c.Lost, c.ForbiddenWord,
+		o.verdict.Lost, o.verdict.Fragment, o.verdict.ForbiddenWord, o.verdict.Reason, o.verdict.Confidence)
+}
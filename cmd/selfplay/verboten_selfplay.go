@@ -0,0 +1,250 @@
+// Command selfplay pits an AI describer against the AI guesser of the text
+// mode, over the whole deck, to find the cards which are too easy, too hard
+// or broken before players see them.
+//
+// The describer is judged by the same judge as the human players of the CLI.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/genai"
+
+	"github.com/Deleplace/verboten/deck"
+	"github.com/Deleplace/verboten/textgame"
+)
+
+var (
+	wordsFile      = flag.String("words", "assets/words.json", "deck file")
+	langs          = flag.String("lang", "", "comma-separated languages to play (default all)")
+	games          = flag.Int("games", 1, "number of games per card")
+	model          = flag.String("model", textgame.DefaultModel, "model of the guesser and of the judge")
+	describerModel = flag.String("describer-model", textgame.DefaultModel, "model of the describer")
+	baseURL        = flag.String("base-url", "", "Gemini API base URL, e.g. the local stand-in http://localhost:8081/")
+	concurrency    = flag.Int("concurrency", 4, "number of games played concurrently")
+	jsonOutput     = flag.Bool("json", false, "print the stats as JSON instead of a table")
+)
+
+// maxGuesses is the number of guesses in a game, as told to the guesser.
+const maxGuesses = 3
+
+// Thresholds to flag the cards.
+const (
+	easyWinRate      = 0.9
+	easyGuesses      = 1.2
+	hardWinRate      = 0.1
+	brokenViolations = 0.5
+)
+
+var client *genai.Client
+
+var judge *textgame.Judge
+
+type gameResult struct {
+	won       bool
+	guesses   int
+	violation bool
+}
+
+// cardStats are the results of all the games of one card.
+type cardStats struct {
+	Lang       string `json:"lang"`
+	ID         string `json:"id"`
+	Word       string `json:"word"`
+	Games      int    `json:"games"`
+	Wins       int    `json:"wins"`
+	Violations int    `json:"violations"`
+	Errors     int    `json:"errors"`
+	// GuessesOnWins is the total number of guesses used in the won games.
+	GuessesOnWins int `json:"guessesOnWins"`
+
+	WinRate       float64 `json:"winRate"`
+	AvgGuesses    float64 `json:"avgGuesses"`
+	ViolationRate float64 `json:"violationRate"`
+	// Flag is "too easy", "too hard", "broken", or empty.
+	Flag string `json:"flag,omitempty"`
+}
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+	ctx := context.Background()
+
+	var err error
+	client, err = textgame.NewClient(ctx, *baseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	judge = &textgame.Judge{Client: client, Model: *model}
+
+	words, err := deck.Load(*wordsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var languages []string
+	if *langs != "" {
+		languages = strings.Split(*langs, ",")
+	} else {
+		for lang := range words {
+			if textgame.LanguageNames[lang] != "" {
+				languages = append(languages, lang)
+			}
+		}
+		slices.Sort(languages)
+	}
+
+	var stats []*cardStats
+	var mu sync.Mutex // protects the stats
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(*concurrency)
+	for _, lang := range languages {
+		if textgame.LanguageNames[lang] == "" {
+			log.Fatalf("unsupported language %q", lang)
+		}
+		for _, c := range words[lang] {
+			st := &cardStats{Lang: lang, ID: c.ID, Word: c.Word}
+			stats = append(stats, st)
+			card := &textgame.Card{Card: c, LanguageName: textgame.LanguageNames[lang]}
+			for range *games {
+				g.Go(func() error {
+					res, err := playGame(ctx, lang, card)
+					mu.Lock()
+					defer mu.Unlock()
+					if err != nil {
+						log.Printf("%s/%s: %v", lang, card.ID, err)
+						st.Errors++
+						return nil
+					}
+					st.add(res)
+					return nil
+				})
+			}
+		}
+	}
+	g.Wait()
+
+	for _, st := range stats {
+		st.summarize()
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(stats)
+		return
+	}
+	printTable(stats)
+}
+
+// playGame lets the describer give up to maxGuesses clues to the guesser.
+func playGame(ctx context.Context, lang string, card *textgame.Card) (gameResult, error) {
+	describer := &chat{
+		model: *describerModel,
+		config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(textgame.DescriberInstructions(card), genai.RoleUser),
+		},
+	}
+	guesser := &chat{
+		model: *model,
+		config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(textgame.GuesserInstructions[lang], genai.RoleUser),
+		},
+	}
+
+	prompt := "Give your first clue."
+	for turn := 1; turn <= maxGuesses; turn++ {
+		clue, err := describer.send(ctx, prompt)
+		if err != nil {
+			return gameResult{}, fmt.Errorf("describer: %w", err)
+		}
+
+		verdict, err := judge.SaidForbidden(ctx, card, clue)
+		if err != nil {
+			return gameResult{}, fmt.Errorf("judge: %w", err)
+		}
+		if verdict.Lost {
+			log.Printf("%s/%s: describer said %q (%s) in %q", lang, card.ID, verdict.Fragment, verdict.ForbiddenWord, clue)
+			return gameResult{violation: true, guesses: turn}, nil
+		}
+
+		guess, err := guesser.send(ctx, clue)
+		if err != nil {
+			return gameResult{}, fmt.Errorf("guesser: %w", err)
+		}
+		if card.IsWinning(guess) {
+			return gameResult{won: true, guesses: turn}, nil
+		}
+		prompt = fmt.Sprintf("Wrong guess: %s. Give another clue.", strings.TrimSpace(guess))
+	}
+	return gameResult{guesses: maxGuesses}, nil
+}
+
+// chat is a conversation with a model. Like in the CLI, each turn is a call
+// with the whole history, bounded by the textgame.DefaultPolicy.
+type chat struct {
+	model   string
+	config  *genai.GenerateContentConfig
+	history []*genai.Content
+}
+
+// send tells text to the model, and returns its reply.
+func (c *chat) send(ctx context.Context, text string) (string, error) {
+	contents := append(slices.Clip(c.history), genai.NewContentFromText(text, genai.RoleUser))
+	resp, err := textgame.Generate(ctx, client, c.model, contents, c.config)
+	if err != nil {
+		return "", err
+	}
+	reply, _ := textgame.TextOf(resp) // checked by Generate
+	c.history = append(contents, genai.NewContentFromText(reply, genai.RoleModel))
+	return reply, nil
+}
+
+func (st *cardStats) add(res gameResult) {
+	st.Games++
+	if res.won {
+		st.Wins++
+		st.GuessesOnWins += res.guesses
+	}
+	if res.violation {
+		st.Violations++
+	}
+}
+
+func (st *cardStats) summarize() {
+	if st.Games == 0 {
+		return
+	}
+	st.WinRate = float64(st.Wins) / float64(st.Games)
+	st.ViolationRate = float64(st.Violations) / float64(st.Games)
+	if st.Wins > 0 {
+		st.AvgGuesses = float64(st.GuessesOnWins) / float64(st.Wins)
+	}
+	switch {
+	case st.ViolationRate >= brokenViolations:
+		st.Flag = "broken"
+	case st.WinRate >= easyWinRate && st.AvgGuesses <= easyGuesses:
+		st.Flag = "too easy"
+	case st.WinRate <= hardWinRate:
+		st.Flag = "too hard"
+	}
+}
+
+func printTable(stats []*cardStats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LANG\tID\tWORD\tGAMES\tWIN%\tGUESSES\tVIOLATION%\tERRORS\tFLAG")
+	for _, st := range stats {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.0f\t%.1f\t%.0f\t%d\t%s\n",
+			st.Lang, st.ID, st.Word, st.Games, 100*st.WinRate, st.AvgGuesses, 100*st.ViolationRate, st.Errors, st.Flag)
+	}
+	w.Flush()
+}
@@ -0,0 +1,161 @@
+// Command standin is a local stand-in for the Gemini API, to run the text mode
+// tools (selfplay, ...) without a network connection nor model costs.
+//
+// It doesn't understand anything: it answers with the longest word of the
+// last user message. Structured outputs get false booleans and zero numbers,
+// the first value of the enums, and that word in the strings and arrays.
+// Streamed responses come a few characters at a time.
+//
+// Point the tools at it with e.g. -base-url=http://localhost:8081/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var addr = flag.String("addr", "localhost:8081", "address to listen on")
+
+// Only the fields of the Gemini API requests and responses used by the stand-in.
+
+type part struct {
+	Text string `json:"text"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type generateContentRequest struct {
+	Contents         []content `json:"contents"`
+	GenerationConfig struct {
+		ResponseMIMEType   string          `json:"responseMimeType"`
+		ResponseJsonSchema json.RawMessage `json:"responseJsonSchema"`
+	} `json:"generationConfig"`
+}
+
+type candidate struct {
+	Content      content `json:"content"`
+	FinishReason string  `json:"finishReason"`
+}
+
+type generateContentResponse struct {
+	Candidates []candidate `json:"candidates"`
+}
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+
+	// e.g. POST /v1beta/models/gemini-2.5-flash-lite:generateContent
+	http.HandleFunc("POST /{version}/models/{call}", func(w http.ResponseWriter, r *http.Request) {
+		model, method, _ := strings.Cut(r.PathValue("call"), ":")
+		if method != "generateContent" && method != "streamGenerateContent" {
+			http.Error(w, "unsupported method "+method, http.StatusNotImplemented)
+			return
+		}
+		var req generateContentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		answer := respond(&req)
+		log.Printf("%s: %q", model, answer)
+
+		if method == "streamGenerateContent" {
+			stream(w, answer)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(generateContentResponse{
+			Candidates: []candidate{{
+				Content:      content{Role: "model", Parts: []part{{Text: answer}}},
+				FinishReason: "STOP",
+			}},
+		})
+	})
+
+	log.Printf("Gemini API stand-in listening on http://%s/", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// streamChunk is the number of characters of each chunk of a streamed response.
+const streamChunk = 3
+
+// stream sends the answer as server-sent events, a few characters at a time.
+func stream(w http.ResponseWriter, answer string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	runes := []rune(answer)
+	for i := 0; i < len(runes); i += streamChunk {
+		c := candidate{
+			Content: content{Role: "model", Parts: []part{{Text: string(runes[i:min(i+streamChunk, len(runes))])}}},
+		}
+		if i+streamChunk >= len(runes) {
+			c.FinishReason = "STOP"
+		}
+		data, _ := json.Marshal(generateContentResponse{Candidates: []candidate{c}})
+		w.Write([]byte("data: " + string(data) + "\r\n\r\n"))
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func respond(req *generateContentRequest) string {
+	var last string
+	for _, c := range req.Contents {
+		if c.Role == "user" && len(c.Parts) > 0 {
+			last = c.Parts[len(c.Parts)-1].Text
+		}
+	}
+	word := longestWord(last)
+
+	if req.GenerationConfig.ResponseMIMEType == "application/json" {
+		var schema any
+		json.Unmarshal(req.GenerationConfig.ResponseJsonSchema, &schema)
+		answer, _ := json.Marshal(fill(schema, word))
+		return string(answer)
+	}
+	return word
+}
+
+// fill returns a value conforming to the JSON schema, with word in all the strings.
+func fill(schema any, word string) any {
+	s, _ := schema.(map[string]any)
+	if enum, _ := s["enum"].([]any); len(enum) > 0 {
+		return enum[0]
+	}
+	typ, _ := s["type"].(string)
+	switch strings.ToLower(typ) {
+	case "object":
+		obj := map[string]any{}
+		props, _ := s["properties"].(map[string]any)
+		for name, prop := range props {
+			obj[name] = fill(prop, word)
+		}
+		return obj
+	case "array":
+		return []any{fill(s["items"], word)}
+	case "boolean":
+		return false
+	case "number", "integer":
+		return 0
+	default:
+		return word
+	}
+}
+
+func longestWord(s string) string {
+	var longest string
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len([]rune(word)) > len([]rune(longest)) {
+			longest = word
+		}
+	}
+	return longest
+}
@@ -0,0 +1,243 @@
+package verboten
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deleplace/verboten/deck"
+)
+
+// modeDaily is the daily challenge: the same card for everyone on a given
+// day, one attempt per player.
+const modeDaily = "daily"
+
+// dailyCookie holds the token which identifies the players of the daily
+// challenge. Unlike the "player" query parameter, it is issued by the server,
+// so that a player can't make another attempt by changing their ID.
+const dailyCookie = "verboten_daily"
+
+// dailyChallenges holds the attempts at the challenges of the current day.
+type dailyChallenges struct {
+	// key signs the tokens of the players. The attempts are kept in memory,
+	// so the tokens don't need to outlive the server either.
+	key []byte
+
+	mu  sync.Mutex
+	day string
+	// attempts by language, then by player token
+	attempts map[string]map[string]*dailyAttempt
+}
+
+// dailyAttempt is the outcome of a player's daily challenge. It has no
+// result yet while the round is being played, or if the player left before
+// the verdict.
+type dailyAttempt struct {
+	Result *roundResult `json:"result,omitempty"`
+}
+
+// dailyResults is how everyone did on the daily challenge of a language.
+type dailyResults struct {
+	Day     string `json:"day"`
+	Lang    string `json:"lang"`
+	Players int    `json:"players"`
+	Wins    int    `json:"wins"`
+	// Word is revealed only to the players who have made their attempt.
+	Word string `json:"word,omitempty"`
+	// You is the attempt of the requesting player, if any.
+	You *dailyAttempt `json:"you,omitempty"`
+	// Results are the outcomes of all the finished attempts, fastest wins first.
+	Results []roundResult `json:"results"`
+}
+
+func newDailyChallenges() *dailyChallenges {
+	return &dailyChallenges{
+		key:      randomBytes(32),
+		attempts: make(map[string]map[string]*dailyAttempt),
+	}
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	crand.Read(b)
+	return b
+}
+
+// sign returns the signature of the player ID, in hex.
+func (dc *dailyChallenges) sign(id string) string {
+	mac := hmac.New(sha256.New, dc.key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// playerOf returns the player of the daily challenge cookie of the request,
+// or "" if it has no valid cookie.
+func (dc *dailyChallenges) playerOf(r *http.Request) string {
+	cookie, err := r.Cookie(dailyCookie)
+	if err != nil {
+		return ""
+	}
+	id, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(dc.sign(id))) {
+		return ""
+	}
+	return id
+}
+
+// identify returns the player of the daily challenge cookie of the request,
+// issuing a new cookie if it has no valid one.
+func (dc *dailyChallenges) identify(w http.ResponseWriter, r *http.Request) string {
+	if id := dc.playerOf(r); id != "" {
+		return id
+	}
+	id := hex.EncodeToString(randomBytes(16))
+	http.SetCookie(w, &http.Cookie{
+		Name:     dailyCookie,
+		Value:    id + "." + dc.sign(id),
+		Path:     "/",
+		MaxAge:   365 * 24 * 3600,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// today is the day of the daily challenge, in UTC.
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
+
+// dailyCard is the card of the daily challenge: the same for everyone on a
+// given day, as long as the deck is unchanged.
+func dailyCard(words deck.Deck, lang, day string) (deck.Card, error) {
+	h := fnv.New64a()
+	h.Write([]byte(day))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	cards, err := words.Draw(lang, 1, r)
+	if err != nil {
+		return deck.Card{}, err
+	}
+	return cards[0], nil
+}
+
+// attemptsLocked returns the attempts of the language, for the current day.
+func (dc *dailyChallenges) attemptsLocked(lang string) map[string]*dailyAttempt {
+	if day := today(); day != dc.day {
+		// A new day, a new challenge
+		dc.day = day
+		clear(dc.attempts)
+	}
+	if dc.attempts[lang] == nil {
+		dc.attempts[lang] = make(map[string]*dailyAttempt)
+	}
+	return dc.attempts[lang]
+}
+
+// played tells if the player has already made their attempt today.
+func (dc *dailyChallenges) played(lang, player string) bool {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.attemptsLocked(lang)[player] != nil
+}
+
+// start registers the attempt of the player, and returns it. It returns nil
+// if the player has already made their attempt today.
+func (dc *dailyChallenges) start(lang, player string) *dailyAttempt {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	attempts := dc.attemptsLocked(lang)
+	if attempts[player] != nil {
+		return nil
+	}
+	a := &dailyAttempt{}
+	attempts[player] = a
+	return a
+}
+
+// finish records the result of an attempt.
+func (dc *dailyChallenges) finish(a *dailyAttempt, result roundResult) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	a.Result = &result
+}
+
+func (dc *dailyChallenges) results(lang, player string) dailyResults {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	attempts := dc.attemptsLocked(lang)
+	res := dailyResults{
+		Day:     dc.day,
+		Lang:    lang,
+		Players: len(attempts),
+		Results: []roundResult{},
+	}
+	for id, a := range attempts {
+		if id == player {
+			you := *a
+			res.You = &you
+		}
+		if a.Result == nil {
+			continue
+		}
+		result := *a.Result
+		result.Word = "" // no spoiler, see dailyResults.Word
+		res.Results = append(res.Results, result)
+		if result.Won {
+			res.Wins++
+		}
+	}
+	slices.SortFunc(res.Results, func(a, b roundResult) int {
+		switch {
+		case a.Won != b.Won:
+			if a.Won {
+				return -1
+			}
+			return 1
+		case a.Seconds < b.Seconds:
+			return -1
+		case a.Seconds > b.Seconds:
+			return 1
+		}
+		return 0
+	})
+	return res
+}
+
+// serveDailyResults responds with the results of today's challenge in the
+// language of the path, as seen by the player of the daily challenge cookie.
+func (vg *VerbotenGameServer) serveDailyResults(w http.ResponseWriter, r *http.Request) {
+	lang := r.PathValue("lang")
+	if _, ok := guesserPrompts[lang]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	res := vg.daily.results(lang, vg.daily.identify(w, r))
+	if res.You != nil {
+		card, err := dailyCard(vg.words, lang, res.Day)
+		if err == nil {
+			res.Word = card.Word
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// serveDaily serves the game page, showing the results of today's challenge.
+func (vg *VerbotenGameServer) serveDaily(w http.ResponseWriter, r *http.Request) {
+	lang := r.PathValue("lang")
+	if _, ok := guesserPrompts[lang]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	vg.daily.identify(w, r)
+	vg.renderGame(w, gamePage{Lang: lang, Daily: true})
+}
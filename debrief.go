@@ -0,0 +1,146 @@
+package verboten
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Deleplace/verboten/textgame"
+)
+
+// maxArchivedGames is the number of recent games kept for their debriefs.
+const maxArchivedGames = 1000
+
+// languageNames are the names of the game languages, for the debriefs.
+var languageNames = map[string]string{
+	"en": "English",
+	"fr": "French",
+	"ar": "Arabic",
+}
+
+// gameArchive keeps the rounds of the recent games, so that the players can
+// ask for a debrief after the game. They are kept in memory, and lost when
+// the server restarts.
+type gameArchive struct {
+	mu    sync.Mutex
+	games map[string][]archivedRound
+	order []string // game IDs, oldest first
+}
+
+// archivedRound is a round played, and its debrief once asked for.
+type archivedRound struct {
+	report  textgame.RoundReport
+	result  roundResult
+	debrief *textgame.Debrief
+}
+
+// debriefRound is a round in the response of the debrief endpoint.
+type debriefRound struct {
+	Round int `json:"round"`
+	roundResult
+	Debrief *textgame.Debrief `json:"debrief"`
+}
+
+func newGameArchive() *gameArchive {
+	return &gameArchive{
+		games: make(map[string][]archivedRound),
+	}
+}
+
+// add archives a round of the game of the given ID. The first round replaces
+// any older game of the same ID.
+func (ga *gameArchive) add(id string, round int, ar archivedRound) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	if _, ok := ga.games[id]; !ok || round == 0 {
+		if !ok {
+			ga.order = append(ga.order, id)
+		}
+		ga.games[id] = nil
+	}
+	ga.games[id] = append(ga.games[id], ar)
+	for len(ga.order) > maxArchivedGames {
+		delete(ga.games, ga.order[0])
+		ga.order = ga.order[1:]
+	}
+}
+
+// get returns a copy of the rounds of the game, or nil if it is unknown.
+func (ga *gameArchive) get(id string) []archivedRound {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	rounds, ok := ga.games[id]
+	if !ok {
+		return nil
+	}
+	return append([]archivedRound{}, rounds...)
+}
+
+// setDebrief keeps the debrief of a round, unless the game was replaced.
+func (ga *gameArchive) setDebrief(id string, round int, report textgame.RoundReport, d textgame.Debrief) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	rounds := ga.games[id]
+	if round < len(rounds) && rounds[round].report.Card == report.Card {
+		rounds[round].debrief = &d
+	}
+}
+
+// outcome describes the result of a round to the model.
+func outcome(result roundResult) string {
+	switch result.Reason {
+	case reasonGuessed:
+		return "won: the guesser found the word"
+	case reasonForbidden, reasonAIForbidden:
+		return fmt.Sprintf("lost: the describer said the proscribed word %q", result.Said)
+	case reasonRule:
+		return fmt.Sprintf("lost: the describer said %q, which broke the rule %s", result.Said, result.Violation.Rule)
+	case reasonBuzzed:
+		return fmt.Sprintf("lost: the other team caught the describer saying %q, too close to a proscribed word", result.Said)
+	case reasonTimeUp:
+		return "lost: the time was up"
+	default:
+		return "the round was abandoned"
+	}
+}
+
+// serveDebrief responds with the explanation of each round of a recent game,
+// by the model. The debriefs are computed once.
+func (vg *VerbotenGameServer) serveDebrief(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rounds := vg.archive.get(id)
+	if rounds == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	g, ctx := errgroup.WithContext(r.Context())
+	for i := range rounds {
+		if rounds[i].debrief != nil {
+			continue
+		}
+		g.Go(func() error {
+			d, err := textgame.DebriefRound(ctx, vg.genaiClient, textgame.DefaultModel, rounds[i].report)
+			if err != nil {
+				return err
+			}
+			rounds[i].debrief = &d
+			vg.archive.setDebrief(id, i, rounds[i].report, d)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	res := make([]debriefRound, len(rounds))
+	for i, ar := range rounds {
+		res[i] = debriefRound{Round: i + 1, roundResult: ar.result, Debrief: ar.debrief}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
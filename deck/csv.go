@@ -0,0 +1,128 @@
+package deck
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// csvColumns are the columns of the CSV format, in the order they are written.
+// The first four are required when reading.
+var csvColumns = []string{"lang", "id", "word", "forbidden", "accepted", "tags", "difficulty"}
+
+// csvListSeparator separates the words of the list columns, in a single cell.
+const csvListSeparator = ";"
+
+// ParseCSV decodes a deck from CSV, e.g. exported from a spreadsheet.
+//
+// The first row is the header, with the columns lang, id, word, forbidden,
+// and optionally accepted, tags and difficulty, in any order. Each other row is a card.
+// The forbidden words, the accepted answers and the tags are separated by
+// semicolons, e.g. "Cheese; Dough; Pepperoni; Italian".
+func ParseCSV(data []byte) (Deck, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("failed to parse CSV words file: empty file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV words file: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("failed to parse CSV words file: line 1: unknown column %q", name)
+		}
+		if _, dup := index[name]; dup {
+			return nil, fmt.Errorf("failed to parse CSV words file: line 1: duplicate column %q", name)
+		}
+		index[name] = i
+	}
+	for _, name := range csvColumns[:4] {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("failed to parse CSV words file: line 1: missing column %q", name)
+		}
+	}
+
+	d := make(Deck)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// csv.ParseError already tells the line
+			return nil, fmt.Errorf("failed to parse CSV words file: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		cell := func(name string) string {
+			i, ok := index[name]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		lang := cell("lang")
+		c := Card{
+			ID:         cell("id"),
+			Word:       cell("word"),
+			Forbidden:  splitList(cell("forbidden")),
+			Accepted:   splitList(cell("accepted")),
+			Tags:       splitList(cell("tags")),
+			Difficulty: cell("difficulty"),
+		}
+		for _, required := range []struct{ name, value string }{{"lang", lang}, {"id", c.ID}, {"word", c.Word}} {
+			if required.value == "" {
+				return nil, fmt.Errorf("failed to parse CSV words file: line %d: empty %s", line, required.name)
+			}
+		}
+		if c.Forbidden == nil {
+			c.Forbidden = []string{}
+		}
+		d[lang] = append(d[lang], c)
+	}
+	return d, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	list := strings.Split(s, csvListSeparator)
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
+// EncodeCSV encodes the deck in the CSV format of ParseCSV, sorted by language.
+// It fails if a word contains the list separator, as it could not be read back.
+func (d Deck) EncodeCSV() ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write(csvColumns)
+	for _, lang := range slices.Sorted(maps.Keys(d)) {
+		for _, c := range d[lang] {
+			row := []string{lang, c.ID, c.Word}
+			for _, list := range [][]string{c.Forbidden, c.Accepted, c.Tags} {
+				for _, word := range list {
+					if strings.Contains(word, csvListSeparator) {
+						return nil, fmt.Errorf("%s/%s: %q contains %q", lang, c.ID, word, csvListSeparator)
+					}
+				}
+				row = append(row, strings.Join(list, csvListSeparator+" "))
+			}
+			row = append(row, c.Difficulty)
+			w.Write(row)
+		}
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
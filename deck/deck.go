@@ -0,0 +1,206 @@
+// Package deck holds the cards of the game: for each language, a list of
+// words to describe along with their proscribed words.
+package deck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Card is a word to describe, and the words the describer is not allowed to say.
+type Card struct {
+	ID        string   `json:"id" yaml:"id"`
+	Word      string   `json:"word" yaml:"word"`
+	Forbidden []string `json:"forbidden" yaml:"forbidden,flow"`
+	// Accepted are the other answers which win the round, e.g. "K8s" for "Kubernetes".
+	Accepted []string `json:"accepted,omitempty" yaml:"accepted,omitempty,flow"`
+	// Tags are the categories of the card, e.g. "food", "animals".
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty,flow"`
+	// Difficulty is one of Difficulties, or empty if unknown.
+	Difficulty string `json:"difficulty,omitempty" yaml:"difficulty,omitempty"`
+}
+
+// Difficulties are the difficulty levels of the cards, from the easiest.
+var Difficulties = []string{"easy", "medium", "hard"}
+
+// Answers returns the word followed by its accepted answers.
+func (c Card) Answers() []string {
+	return append([]string{c.Word}, c.Accepted...)
+}
+
+// Proscribed returns the answers of the card followed by its forbidden words.
+func (c Card) Proscribed() []string {
+	return append(c.Answers(), c.Forbidden...)
+}
+
+// Deck maps a language code (e.g. "en", "fr") to its cards.
+type Deck map[string][]Card
+
+// Format is the file format of a deck.
+type Format string
+
+const (
+	// JSON is the format of assets/words.json.
+	JSON Format = "json"
+	// CSV has one row per card, see ParseCSV.
+	CSV Format = "csv"
+	// YAML has the structure of the JSON format.
+	YAML Format = "yaml"
+)
+
+// FormatOf returns the format of a deck file, from its extension.
+// It defaults to JSON.
+func FormatOf(filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return CSV
+	case ".yaml", ".yml":
+		return YAML
+	default:
+		return JSON
+	}
+}
+
+// Load reads a deck from a file such as assets/words.json, in the format of
+// its extension.
+func Load(filename string) (Deck, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read words file: %w", err)
+	}
+	return ParseFormat(data, FormatOf(filename))
+}
+
+// ParseFormat decodes a deck in the given format.
+func ParseFormat(data []byte, format Format) (Deck, error) {
+	switch format {
+	case CSV:
+		return ParseCSV(data)
+	case YAML:
+		return ParseYAML(data)
+	default:
+		return Parse(data)
+	}
+}
+
+// Parse decodes a deck in the JSON format of assets/words.json.
+func Parse(data []byte) (Deck, error) {
+	var d Deck
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse words file: %w", err)
+	}
+	return d, nil
+}
+
+// Draw returns n distinct cards of the language lang, in random order.
+// If the language has fewer than n cards, all of them are returned.
+func (d Deck) Draw(lang string, n int, r *rand.Rand) ([]Card, error) {
+	cards := d[lang]
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("no cards for language %q", lang)
+	}
+	n = min(n, len(cards))
+	drawn := make([]Card, n)
+	for i, j := range r.Perm(len(cards))[:n] {
+		drawn[i] = cards[j]
+	}
+	return drawn, nil
+}
+
+// Merge adds the cards of other to the deck. A card with the ID of an
+// existing card of the same language replaces it.
+func (d Deck) Merge(other Deck) {
+	for lang, cards := range other {
+		for _, c := range cards {
+			i := slices.IndexFunc(d[lang], func(old Card) bool { return old.ID == c.ID })
+			if i >= 0 {
+				d[lang][i] = c
+			} else {
+				d[lang] = append(d[lang], c)
+			}
+		}
+	}
+}
+
+// Save writes the deck to a file, in the format of its extension. JSON files
+// have the layout of assets/words.json: one card per line, to keep the diffs
+// of deck edits readable.
+func (d Deck) Save(filename string) error {
+	data, err := d.Encode(FormatOf(filename))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// Encode encodes the deck in the given format.
+func (d Deck) Encode(format Format) ([]byte, error) {
+	switch format {
+	case CSV:
+		return d.EncodeCSV()
+	case YAML:
+		return d.EncodeYAML()
+	default:
+		return d.Marshal(), nil
+	}
+}
+
+// Marshal encodes the deck in the layout of assets/words.json.
+func (d Deck) Marshal() []byte {
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	langs := slices.Sorted(maps.Keys(d))
+	for i, lang := range langs {
+		fmt.Fprintf(&b, "    %s: [\n", quote(lang))
+		for j, c := range d[lang] {
+			fmt.Fprintf(&b, `        { "id": %s, "word": %s, "forbidden": [%s]`,
+				quote(c.ID), quote(c.Word), quoteAll(c.Forbidden))
+			if len(c.Accepted) > 0 {
+				fmt.Fprintf(&b, `, "accepted": [%s]`, quoteAll(c.Accepted))
+			}
+			if len(c.Tags) > 0 {
+				fmt.Fprintf(&b, `, "tags": [%s]`, quoteAll(c.Tags))
+			}
+			if c.Difficulty != "" {
+				fmt.Fprintf(&b, `, "difficulty": %s`, quote(c.Difficulty))
+			}
+			b.WriteString(" }")
+			if j < len(d[lang])-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    ]")
+		if i < len(langs)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// quoteAll encodes each of words as a JSON string, separated by commas.
+func quoteAll(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = quote(w)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quote encodes s as a JSON string, leaving the non-ASCII letters as they are.
+func quote(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,61 @@
+package deck
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	d, err := Load("../assets/words.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, formats := range [][]Format{
+		{JSON},
+		{CSV},
+		{YAML},
+		{JSON, CSV, YAML},
+		{YAML, CSV, JSON},
+	} {
+		got := d
+		for _, format := range formats {
+			data, err := got.Encode(format)
+			if err != nil {
+				t.Fatalf("%v: encode %s: %v", formats, format, err)
+			}
+			if got, err = ParseFormat(data, format); err != nil {
+				t.Fatalf("%v: parse %s: %v", formats, format, err)
+			}
+		}
+		for lang, cards := range d {
+			if !reflect.DeepEqual(got[lang], cards) {
+				t.Errorf("%v: the %s cards changed:\n got %+v\nwant %+v", formats, lang, got[lang], cards)
+			}
+		}
+		if len(got) != len(d) {
+			t.Errorf("%v: %d languages, want %d", formats, len(got), len(d))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		format Format
+		data   string
+		// want is a part of the error message, telling the line.
+		want string
+	}{
+		{CSV, "lang,id,word,forbidden\nen,pizza,Pizza,Cheese\nen,cat,Cat\n", "line 3"},
+		{CSV, "lang,id,word,forbidden\nen,pizza,Pizza,Cheese\nen,cat,,Meow\n", "line 3: empty word"},
+		{CSV, "lang,id,wrod,forbidden\n", "line 1: unknown column \"wrod\""},
+		{CSV, "lang,id,forbidden\n", "line 1: missing column \"word\""},
+		{YAML, "en:\n  - id: pizza\n    word: Pizza\n      forbidden: [Cheese]\n", "line 4"},
+		{YAML, "en:\n  - id: pizza\n    wrod: Pizza\n", "line 3"},
+	} {
+		_, err := ParseFormat([]byte(tc.data), tc.format)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s %q: error %v, want one with %q", tc.format, tc.data, err, tc.want)
+		}
+	}
+}
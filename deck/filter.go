@@ -0,0 +1,67 @@
+package deck
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Filter selects cards by tag and difficulty, e.g. for themed rounds or
+// kids' sessions. The zero Filter selects all the cards.
+type Filter struct {
+	// Tags, if not empty, selects the cards having any of the tags.
+	Tags []string
+	// Difficulties, if not empty, selects the cards of any of these levels.
+	Difficulties []string
+}
+
+// ParseFilter returns the filter of comma-separated tags and difficulties,
+// as given in command line flags or query parameters.
+func ParseFilter(tags, difficulties string) (Filter, error) {
+	f := Filter{
+		Tags:         splitComma(tags),
+		Difficulties: splitComma(difficulties),
+	}
+	for _, level := range f.Difficulties {
+		if !slices.Contains(Difficulties, level) {
+			return Filter{}, fmt.Errorf("unknown difficulty %q, expected one of %s", level, strings.Join(Difficulties, ", "))
+		}
+	}
+	return f, nil
+}
+
+func splitComma(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Match tells if the card is selected by the filter.
+func (f Filter) Match(c Card) bool {
+	if len(f.Difficulties) > 0 && !slices.Contains(f.Difficulties, c.Difficulty) {
+		return false
+	}
+	if len(f.Tags) > 0 && !slices.ContainsFunc(c.Tags, func(tag string) bool {
+		return slices.Contains(f.Tags, strings.ToLower(tag))
+	}) {
+		return false
+	}
+	return true
+}
+
+// Filter returns the cards of the deck selected by f.
+func (d Deck) Filter(f Filter) Deck {
+	filtered := make(Deck, len(d))
+	for lang, cards := range d {
+		for _, c := range cards {
+			if f.Match(c) {
+				filtered[lang] = append(filtered[lang], c)
+			}
+		}
+	}
+	return filtered
+}
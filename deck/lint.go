@@ -0,0 +1,81 @@
+package deck
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// validID is the format of the card IDs, which are also image file names and URL paths.
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Lint checks the content of the deck, and returns a description of each
+// problem found: empty fields, malformed or duplicate IDs, forbidden words
+// repeating the target word, and cards missing in some languages.
+// The problems are prefixed by "lang/id: ".
+func (d Deck) Lint() []string {
+	var problems []string
+	report := func(lang, id, format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("%s/%s: ", lang, id)+fmt.Sprintf(format, args...))
+	}
+
+	langs := slices.Sorted(maps.Keys(d))
+	// For each card ID, the languages that have it
+	idLangs := make(map[string][]string)
+	for _, lang := range langs {
+		if len(d[lang]) == 0 {
+			report(lang, "", "no cards")
+		}
+		seen := make(map[string]bool)
+		for i, c := range d[lang] {
+			id := c.ID
+			switch {
+			case id == "":
+				id = fmt.Sprintf("#%d", i)
+				report(lang, id, "empty id")
+			case !validID.MatchString(id):
+				report(lang, id, "id must be lowercase letters, digits, - or _")
+			case seen[id]:
+				report(lang, id, "duplicate id")
+			}
+			if c.ID != "" && !seen[c.ID] {
+				seen[c.ID] = true
+				idLangs[c.ID] = append(idLangs[c.ID], lang)
+			}
+
+			if strings.TrimSpace(c.Word) == "" {
+				report(lang, id, "empty word")
+			}
+			if c.Difficulty != "" && !slices.Contains(Difficulties, c.Difficulty) {
+				report(lang, id, "difficulty %q is not one of %s", c.Difficulty, strings.Join(Difficulties, ", "))
+			}
+			if len(c.Forbidden) == 0 {
+				report(lang, id, "no forbidden words")
+			}
+			seenForbidden := make(map[string]bool)
+			for _, f := range c.Forbidden {
+				key := strings.ToLower(strings.TrimSpace(f))
+				switch {
+				case key == "":
+					report(lang, id, "empty forbidden word")
+				case key == strings.ToLower(strings.TrimSpace(c.Word)):
+					report(lang, id, "forbidden word %q repeats the word", f)
+				case seenForbidden[key]:
+					report(lang, id, "duplicate forbidden word %q", f)
+				}
+				seenForbidden[key] = true
+			}
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(idLangs)) {
+		for _, lang := range langs {
+			if !slices.Contains(idLangs[id], lang) {
+				report(lang, id, "missing card, present in %s", strings.Join(idLangs[id], ", "))
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,41 @@
+package deck
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseYAML decodes a deck in YAML, with the structure of the JSON format:
+//
+//	en:
+//	  - id: pizza
+//	    word: Pizza
+//	    forbidden: [Cheese, Dough, Pepperoni, Italian]
+//
+// Unknown fields are errors, to catch typos.
+func ParseYAML(data []byte) (Deck, error) {
+	var d Deck
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&d); err != nil {
+		// The YAML errors tell the line
+		return nil, fmt.Errorf("failed to parse YAML words file: %w", err)
+	}
+	return d, nil
+}
+
+// EncodeYAML encodes the deck in YAML, sorted by language.
+func (d Deck) EncodeYAML() ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(d); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+package verboten
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/Deleplace/verboten/deck"
+)
+
+const (
+	// maxDeckUpload is the maximum size of an uploaded deck, images included.
+	maxDeckUpload = 10 << 20
+	// maxCustomDecks is the maximum number of custom decks kept in memory.
+	maxCustomDecks = 1000
+)
+
+// deckStore holds the custom decks uploaded by the players, by deck ID.
+// They are kept in memory, and lost when the server restarts.
+type deckStore struct {
+	mu    sync.Mutex
+	decks map[string]*customDeck
+}
+
+// customDeck is an uploaded deck, along with the images of its cards.
+type customDeck struct {
+	words deck.Deck
+	// images are the PNG images, by card ID.
+	images map[string][]byte
+}
+
+func newDeckStore() *deckStore {
+	return &deckStore{
+		decks: make(map[string]*customDeck),
+	}
+}
+
+// add stores cd under a new random deck ID, and returns the ID.
+func (ds *deckStore) add(cd *customDeck) (string, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if len(ds.decks) >= maxCustomDecks {
+		return "", errors.New("too many custom decks")
+	}
+	id := randomString(8)
+	for ds.decks[id] != nil {
+		id = randomString(8)
+	}
+	ds.decks[id] = cd
+	return id, nil
+}
+
+func (ds *deckStore) get(id string) *customDeck {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.decks[id]
+}
+
+// deckFor returns the cards selected by the filter in the custom deck of the
+// given ID, or in the built-in deck if the ID is empty.
+func (vg *VerbotenGameServer) deckFor(deckID string, filter deck.Filter) (deck.Deck, error) {
+	if deckID == "" {
+		return vg.words.Filter(filter), nil
+	}
+	cd := vg.decks.get(deckID)
+	if cd == nil {
+		return nil, fmt.Errorf("unknown deck %q", deckID)
+	}
+	return cd.words.Filter(filter), nil
+}
+
+// requestFilter returns the card filter of the "tags" and "difficulty"
+// parameters of the request, e.g. ?tags=food,animals&difficulty=easy
+func requestFilter(r *http.Request) (deck.Filter, error) {
+	return deck.ParseFilter(r.FormValue("tags"), r.FormValue("difficulty"))
+}
+
+// cardImageURL is the URL of the image of a card of the given deck.
+func cardImageURL(deckID, cardID string) string {
+	if deckID == "" {
+		return "/forbiddenwords/words_img/" + cardID + ".png"
+	}
+	return "/api/decks/" + deckID + "/images/" + cardID + ".png"
+}
+
+// uploadDeck stores a custom deck, and responds with its ID.
+//
+// The request is either a deck in the schema of assets/words.json, or a
+// multipart form with the deck in the "words" file and the optional card
+// images in "images" files named {id}.png. The deck may also be in CSV or YAML,
+// as told by its Content-Type or by the extension of the "words" file.
+func (vg *VerbotenGameServer) uploadDeck(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxDeckUpload)
+	cd := &customDeck{
+		images: make(map[string][]byte),
+	}
+
+	var data []byte
+	var err error
+	format := deckFormat(r.Header.Get("Content-Type"))
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxDeckUpload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err = readFormFile(r.MultipartForm.File["words"])
+		if fhs := r.MultipartForm.File["words"]; len(fhs) > 0 {
+			format = deck.FormatOf(fhs[0].Filename)
+		}
+		for _, fh := range r.MultipartForm.File["images"] {
+			cardID, ok := strings.CutSuffix(path.Base(fh.Filename), ".png")
+			if !ok {
+				http.Error(w, fmt.Sprintf("image %q is not a .png file", fh.Filename), http.StatusBadRequest)
+				return
+			}
+			img, err := readFormFile([]*multipart.FileHeader{fh})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			cd.images[cardID] = img
+		}
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cd.words, err = deck.ParseFormat(data, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for lang := range cd.words {
+		if _, ok := guesserPrompts[lang]; !ok {
+			http.Error(w, fmt.Sprintf("unsupported language %q", lang), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(cd.words) == 0 {
+		http.Error(w, "empty deck", http.StatusBadRequest)
+		return
+	}
+	if problems := cd.words.Lint(); len(problems) > 0 {
+		http.Error(w, strings.Join(problems, "\n"), http.StatusBadRequest)
+		return
+	}
+
+	id, err := vg.decks.add(cd)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	log.Printf("Deck %s uploaded with %d languages and %d images", id, len(cd.words), len(cd.images))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":  id,
+		"url": "/?deck=" + id,
+	})
+}
+
+// deckFormat returns the deck format of a Content-Type, defaulting to JSON.
+func deckFormat(contentType string) deck.Format {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return deck.CSV
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		return deck.YAML
+	default:
+		return deck.JSON
+	}
+}
+
+func readFormFile(fhs []*multipart.FileHeader) ([]byte, error) {
+	if len(fhs) == 0 {
+		return nil, errors.New("missing words file")
+	}
+	f, err := fhs[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// serveDeckImage serves the image of a card of a custom deck.
+func (vg *VerbotenGameServer) serveDeckImage(w http.ResponseWriter, r *http.Request) {
+	cd := vg.decks.get(r.PathValue("deckID"))
+	if cd == nil {
+		http.NotFound(w, r)
+		return
+	}
+	cardID, _ := strings.CutSuffix(r.PathValue("image"), ".png")
+	img, ok := cd.images[cardID]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(img)
+}
@@ -7,6 +7,7 @@ require (
 	golang.org/x/sync v0.18.0
 	golang.org/x/text v0.30.0
 	google.golang.org/genai v1.36.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
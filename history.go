@@ -0,0 +1,290 @@
+package verboten
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Deleplace/verboten/deck"
+)
+
+const (
+	// initialRating is the Elo rating of a new card or player.
+	initialRating = 1500
+	// eloK is how much a single round moves the ratings.
+	eloK = 32
+	// minStatsGames is the number of games below which no advice is given on a card.
+	minStatsGames = 10
+	// adaptiveChoice is how many more candidate cards than rounds are
+	// considered when matching the cards to the player's skill.
+	adaptiveChoice = 3
+)
+
+// roundRecord is the outcome of one classic round of the built-in deck,
+// as stored in the game history.
+type roundRecord struct {
+	Time   time.Time `json:"time"`
+	Lang   string    `json:"lang"`
+	Card   string    `json:"card"`
+	Player string    `json:"player,omitempty"` // anonymous if empty
+	Won    bool      `json:"won"`
+	Reason string    `json:"reason"`
+	// Seconds is the time to guess, for won rounds.
+	Seconds float64 `json:"seconds,omitempty"`
+}
+
+// cardKey identifies a card of the built-in deck.
+type cardKey struct {
+	lang, id string
+}
+
+// cardStats are the statistics of a card, from the game history.
+type cardStats struct {
+	Lang       string  `json:"lang"`
+	ID         string  `json:"id"`
+	Word       string  `json:"word,omitempty"`
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	Violations int     `json:"violations"`
+	Rating     float64 `json:"rating"`
+
+	WinRate       float64 `json:"winRate"`
+	AvgSeconds    float64 `json:"avgSecondsToGuess"`
+	ViolationRate float64 `json:"violationRate"`
+	// Difficulty is estimated from the rating: easy, medium or hard.
+	Difficulty string `json:"estimatedDifficulty"`
+	// Advice is "reword", "retire", or empty.
+	Advice string `json:"advice,omitempty"`
+
+	guessSeconds float64 // total, over the won games
+}
+
+// history records the outcomes of the rounds, and rates the cards and the
+// players with the Elo system: each round is a game between the describer
+// and the card. The records are appended to a JSON Lines file, if any, and
+// replayed when the server starts.
+type history struct {
+	mu      sync.Mutex
+	file    *os.File // nil to keep the history in memory only
+	cards   map[cardKey]*cardStats
+	players map[string]float64
+}
+
+func newHistory() *history {
+	return &history{
+		cards:   make(map[cardKey]*cardStats),
+		players: make(map[string]float64),
+	}
+}
+
+// open replays the records of the history file, and appends the new records to it.
+func (h *history) open(filename string) error {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	scanner := bufio.NewScanner(f)
+	n := 0
+	for scanner.Scan() {
+		var rec roundRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to parse history line %d: %w", n+1, err)
+		}
+		h.applyLocked(rec)
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	h.file = f
+	log.Printf("Replayed %d rounds of history from %s", n, filename)
+	return nil
+}
+
+// record adds the outcome of a round to the history.
+func (h *history) record(rec roundRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.applyLocked(rec)
+	if h.file == nil {
+		return
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if _, err := h.file.Write(append(data, '\n')); err != nil {
+		log.Printf("failed to write history: %v", err)
+	}
+}
+
+func (h *history) applyLocked(rec roundRecord) {
+	key := cardKey{rec.Lang, rec.Card}
+	cs := h.cards[key]
+	if cs == nil {
+		cs = &cardStats{Lang: rec.Lang, ID: rec.Card, Rating: initialRating}
+		h.cards[key] = cs
+	}
+	cs.Games++
+	if rec.Won {
+		cs.Wins++
+		cs.guessSeconds += rec.Seconds
+	}
+	if rec.Reason == reasonForbidden || rec.Reason == reasonBuzzed {
+		cs.Violations++
+	}
+
+	// Elo update: the player wins against the card when the card is guessed
+	playerRating := float64(initialRating)
+	if rec.Player != "" {
+		if r, ok := h.players[rec.Player]; ok {
+			playerRating = r
+		}
+	}
+	expected := 1 / (1 + math.Pow(10, (cs.Rating-playerRating)/400))
+	actual := 0.0
+	if rec.Won {
+		actual = 1
+	}
+	delta := eloK * (actual - expected)
+	cs.Rating -= delta
+	if rec.Player != "" {
+		h.players[rec.Player] = playerRating + delta
+	}
+}
+
+// playerRating returns the rating of a player, and whether they have played before.
+func (h *history) playerRating(player string) (float64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.players[player]
+	return r, ok
+}
+
+// cardRating returns the rating of a card of the built-in deck.
+func (h *history) cardRating(lang, id string) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cs := h.cards[cardKey{lang, id}]; cs != nil {
+		return cs.Rating
+	}
+	return initialRating
+}
+
+// drawFor returns n distinct cards in random order, among the cards whose
+// rating is the closest to the player's rating. Unknown players get random cards.
+func (h *history) drawFor(player, lang string, words deck.Deck, n int, r *rand.Rand) ([]deck.Card, error) {
+	rating, ok := h.playerRating(player)
+	if player == "" || !ok {
+		return words.Draw(lang, n, r)
+	}
+	candidates := slices.Clone(words[lang])
+	// Shuffle first, so that the cards of equal ratings are drawn fairly
+	r.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	distance := make(map[string]float64, len(candidates))
+	for _, c := range candidates {
+		distance[c.ID] = math.Abs(h.cardRating(lang, c.ID) - rating)
+	}
+	slices.SortStableFunc(candidates, func(a, b deck.Card) int {
+		switch da, db := distance[a.ID], distance[b.ID]; {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	})
+	candidates = candidates[:min(len(candidates), adaptiveChoice*n)]
+	return deck.Deck{lang: candidates}.Draw(lang, n, r)
+}
+
+// stats returns the statistics of the cards of the language, or of all the
+// languages if lang is empty, from the hardest card to the easiest.
+func (h *history) stats(lang string) []cardStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := []cardStats{}
+	for key, cs := range h.cards {
+		if lang != "" && key.lang != lang {
+			continue
+		}
+		s := *cs
+		s.WinRate = float64(s.Wins) / float64(s.Games)
+		s.ViolationRate = float64(s.Violations) / float64(s.Games)
+		if s.Wins > 0 {
+			s.AvgSeconds = s.guessSeconds / float64(s.Wins)
+		}
+		switch {
+		case s.Rating < initialRating-50:
+			s.Difficulty = "easy"
+		case s.Rating > initialRating+50:
+			s.Difficulty = "hard"
+		default:
+			s.Difficulty = "medium"
+		}
+		if s.Games >= minStatsGames {
+			switch {
+			case s.ViolationRate >= 0.5:
+				// The natural description needs a proscribed word
+				s.Advice = "reword"
+			case s.WinRate <= 0.1 || s.WinRate >= 0.95:
+				s.Advice = "retire"
+			}
+		}
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b cardStats) int {
+		switch {
+		case a.Rating > b.Rating:
+			return -1
+		case a.Rating < b.Rating:
+			return 1
+		}
+		return strings.Compare(a.Lang+a.ID, b.Lang+b.ID)
+	})
+	return stats
+}
+
+// maxPlayerID is the maximum length of the player IDs.
+const maxPlayerID = 64
+
+// playerID returns the anonymous ID of the player, from the "player" query
+// parameter. The browser generates it once and keeps it in its local storage.
+func playerID(r *http.Request) string {
+	id := r.URL.Query().Get("player")
+	if len(id) > maxPlayerID {
+		return ""
+	}
+	return id
+}
+
+// serveCardStats responds with the statistics of the cards of the built-in
+// deck, for the optional "lang" query parameter.
+func (vg *VerbotenGameServer) serveCardStats(w http.ResponseWriter, r *http.Request) {
+	stats := vg.history.stats(r.URL.Query().Get("lang"))
+	for i, s := range stats {
+		for _, c := range vg.words[s.Lang] {
+			if c.ID == s.ID {
+				stats[i].Word = c.Word
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
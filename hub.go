@@ -0,0 +1,119 @@
+package verboten
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// hubClientBuffer is the number of messages that may be queued for a slow
+// WebSocket, before it gets disconnected.
+const hubClientBuffer = 256
+
+// hub fans out the messages of a game to all the WebSockets attached to it:
+// the player, and in a room all the members.
+type hub struct {
+	mu      sync.Mutex
+	clients map[*hubClient]bool
+}
+
+// hubClient is a WebSocket attached to a hub. Each client has its own writer
+// goroutine, so that a slow connection doesn't hold back the others.
+type hubClient struct {
+	conn *websocket.Conn
+	send chan []byte
+}
+
+func newHub() *hub {
+	return &hub{
+		clients: make(map[*hubClient]bool),
+	}
+}
+
+// join attaches conn to the hub, and starts writing the published messages to it.
+func (h *hub) join(conn *websocket.Conn) *hubClient {
+	c := &hubClient{
+		conn: conn,
+		send: make(chan []byte, hubClientBuffer),
+	}
+	h.mu.Lock()
+	h.clients[c] = true
+	h.mu.Unlock()
+	go c.writeLoop()
+	return c
+}
+
+// leave detaches c from the hub. It is safe to call several times.
+func (h *hub) leave(c *hubClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(c)
+}
+
+func (h *hub) removeLocked(c *hubClient) {
+	if h.clients[c] {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// publish sends v as JSON to all the clients.
+func (h *hub) publish(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Println("marshal message error: ", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		h.enqueueLocked(c, data)
+	}
+}
+
+// sendTo sends v as JSON to the client c only.
+func (h *hub) sendTo(c *hubClient, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Println("marshal message error: ", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[c] {
+		h.enqueueLocked(c, data)
+	}
+}
+
+func (h *hub) enqueueLocked(c *hubClient, data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		log.Println("client too slow, disconnecting")
+		h.removeLocked(c)
+		c.conn.Close()
+	}
+}
+
+// close disconnects all the clients.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		h.removeLocked(c)
+		c.conn.Close()
+	}
+}
+
+func (c *hubClient) writeLoop() {
+	for data := range c.send {
+		if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			log.Println("write message error: ", err)
+			// Unblocks the reader of the connection, which will leave the hub.
+			c.conn.Close()
+			return
+		}
+	}
+}
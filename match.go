@@ -0,0 +1,649 @@
+package verboten
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/Deleplace/verboten/deck"
+	"github.com/Deleplace/verboten/textgame"
+)
+
+// defaultRounds is the number of cards in a match, when the browser
+// doesn't ask for a specific number.
+const defaultRounds = 3
+
+// roundDuration is the time the human player has to make the AI guess the word,
+// once the referee has announced the card.
+const roundDuration = 30 * time.Second
+
+// speedDuration is the time of a speed round, across all its cards.
+const speedDuration = 60 * time.Second
+
+// Game modes.
+const (
+	// modeClassic: the human describes, the AI guesses.
+	modeClassic = "classic"
+	// modeReverse: the AI describes, the human guesses.
+	modeReverse = "reverse"
+	// modeSpeed: the human describes as many cards as possible before the
+	// time is up. Each verdict deals the next card right away.
+	modeSpeed = "speed"
+)
+
+// Reasons for the end of a round.
+const (
+	reasonGuessed     = "guessed"
+	reasonForbidden   = "forbidden"
+	reasonAIForbidden = "aiForbidden"
+	reasonRule        = "rule"
+	reasonBuzzed      = "buzzed"
+	reasonTimeUp      = "timeUp"
+	reasonAbandoned   = "abandoned"
+)
+
+// match is a series of rounds played over a single hub: by one human over
+// their WebSocket, or by the members of a room taking turns.
+// Each round has its own card, and its own pair of Gemini Live sessions
+// (AI player and judge), so that the AI player doesn't remember the previous
+// round and the judge knows the new proscribed words.
+type match struct {
+	vg     *VerbotenGameServer
+	id     string
+	lang   string
+	prompt string
+	deckID string // empty for the built-in deck
+	cards  []deck.Card
+	hub    *hub
+
+	// reverse is true when the AI describes the cards and the human guesses.
+	// The prompt is then formatted with the card of each round.
+	reverse bool
+
+	// speed is true in a speed round: a single timer runs across the cards,
+	// and the next card is dealt as soon as the current one has a verdict.
+	speed bool
+
+	// rules are the extra rules of the descriptions. They only apply to a
+	// human describer, not in reverse mode.
+	rules []textgame.Rule
+
+	// onVerdict, if not nil, is called after each verdict.
+	onVerdict func(roundResult)
+
+	// mu protects the fields below.
+	mu           sync.Mutex
+	player       string // ID of the describer, for the game history
+	round        int    // index of the current card
+	started      bool   // the current round has been dealt
+	over         bool   // the current round has a verdict
+	finished     bool   // the speed round is over
+	timeUp       bool   // the clock of the speed round has run out
+	score        int
+	results      []roundResult
+	humanSpeech  strings.Builder
+	modelSpeech  strings.Builder
+	humanTurn    strings.Builder // what the human said since the AI last spoke
+	transcript   []textgame.Line // of the current round, for its debrief
+	session      *genai.Session
+	sessionJudge *genai.Session
+	timer        *time.Timer
+	deadline     time.Time // end of the current round, once the timer is started
+}
+
+type roundResult struct {
+	Word   string `json:"word"`
+	Won    bool   `json:"won"`
+	Reason string `json:"reason"`
+	// Said is the proscribed word said by the describer, if any.
+	Said string `json:"said,omitempty"`
+	// Violation is the rule broken by the describer, if any.
+	Violation *textgame.Violation `json:"violation,omitempty"`
+	// Buzzer is the member of the opposing team who caught the describer, in a room.
+	Buzzer *member `json:"buzzer,omitempty"`
+	// Seconds is the time it took to guess the word, for won rounds.
+	Seconds float64 `json:"seconds,omitempty"`
+}
+
+// clientMessage is a message from the browser: either realtime audio input,
+// or a game control.
+type clientMessage struct {
+	genai.LiveRealtimeInput
+	// Ready means the referee has finished announcing the card.
+	Ready bool `json:"ready,omitempty"`
+	// NextRound asks for the next card, after a verdict.
+	// In a room, it starts the turn of the describer.
+	NextRound bool `json:"nextRound,omitempty"`
+	// Buzz challenges the describer, in a room: a member of the opposing
+	// team thinks they said a proscribed word.
+	Buzz bool `json:"buzz,omitempty"`
+}
+
+// gameEvent is a message from the server to the browser. It is sent on the same
+// WebSocket as the raw Gemini Live messages, and is recognized by its "event" field.
+type gameEvent struct {
+	Event   string        `json:"event"` // "round", "timer", "transcript", "judge", "verdict", "matchOver", "room" or "buzz"
+	Game    string        `json:"game,omitempty"`
+	Lang    string        `json:"lang,omitempty"`
+	Mode    string        `json:"mode,omitempty"`
+	Round   int           `json:"round,omitempty"`
+	Rounds  int           `json:"rounds,omitempty"`
+	Card    *deck.Card    `json:"card,omitempty"`
+	Image   string        `json:"image,omitempty"` // URL of the image of the card
+	Seconds int           `json:"seconds,omitempty"`
+	Speaker string        `json:"speaker,omitempty"` // "human" or "ai"
+	Text    string        `json:"text,omitempty"`
+	Result  *roundResult  `json:"result,omitempty"`
+	Score   int           `json:"score"`
+	Results []roundResult `json:"results,omitempty"`
+	Rules   []string      `json:"rules,omitempty"` // specs of the extra rules, see textgame.ParseRules
+	Room    *roomState    `json:"room,omitempty"`
+	Buzz    *buzzRuling   `json:"buzz,omitempty"`
+}
+
+// startRound connects fresh Gemini Live sessions for the current card,
+// and shows the card to the player.
+func (m *match) startRound(ctx context.Context) error {
+	m.mu.Lock()
+	round := m.round
+	card := m.cards[round]
+	score := m.score
+	m.mu.Unlock()
+
+	prompt := m.prompt
+	if m.reverse {
+		prompt = fmt.Sprintf(m.prompt, card.Word, strings.Join(card.Forbidden, ", "))
+	}
+	session, err := m.vg.connectPlayer(ctx, prompt)
+	if err != nil {
+		return fmt.Errorf("connect to player model: %w", err)
+	}
+	sessionJudge, err := m.vg.connectJudge(ctx, card.Proscribed(), m.reverse)
+	if err != nil {
+		session.Close()
+		return fmt.Errorf("connect to judge model: %w", err)
+	}
+
+	m.mu.Lock()
+	if m.finished {
+		// The time of the speed round ran out while connecting
+		m.mu.Unlock()
+		session.Close()
+		sessionJudge.Close()
+		return nil
+	}
+	m.session, m.sessionJudge = session, sessionJudge
+	m.started = true
+	m.over = false
+	if !m.speed {
+		m.timer = nil
+		m.deadline = time.Time{}
+	}
+	deadline := m.deadline
+	m.humanSpeech.Reset()
+	m.modelSpeech.Reset()
+	m.humanTurn.Reset()
+	m.transcript = nil
+	m.mu.Unlock()
+
+	go m.playerLoop(round, session)
+	go m.judgeLoop(round, sessionJudge)
+
+	log.Printf("Game %s round %d/%d with proscribed words %q", m.id, round+1, len(m.cards), card.Proscribed())
+	m.hub.publish(m.roundEvent(round, score))
+	if !deadline.IsZero() {
+		// The clock of the speed round is already running
+		m.hub.publish(gameEvent{Event: "timer", Round: round + 1, Seconds: int(time.Until(deadline) / time.Second)})
+	}
+	return nil
+}
+
+func (m *match) roundEvent(round, score int) gameEvent {
+	event := gameEvent{
+		Event:  "round",
+		Game:   m.id,
+		Lang:   m.lang,
+		Mode:   modeClassic,
+		Round:  round + 1,
+		Rounds: len(m.cards),
+		Score:  score,
+	}
+	if m.speed {
+		// The number of cards depends on the speed of the players
+		event.Mode = modeSpeed
+		event.Rounds = 0
+	}
+	if m.reverse {
+		// The human must guess the card: it is revealed only in the verdict.
+		event.Mode = modeReverse
+	} else {
+		card := m.cards[round]
+		event.Card = &card
+		event.Image = cardImageURL(m.deckID, card.ID)
+		for _, r := range m.rules {
+			event.Rules = append(event.Rules, r.String())
+		}
+	}
+	return event
+}
+
+func (m *match) gameHub() *hub {
+	return m.hub
+}
+
+// snapshot returns the current card, and the remaining time or the verdict.
+func (m *match) snapshot() []gameEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.started || m.round >= len(m.cards) {
+		return nil
+	}
+	events := []gameEvent{m.roundEvent(m.round, m.score)}
+	switch {
+	case m.over:
+		result := m.results[len(m.results)-1]
+		events = append(events, gameEvent{Event: "verdict", Round: m.round + 1, Result: &result, Score: m.score})
+	case !m.deadline.IsZero():
+		events = append(events, gameEvent{Event: "timer", Round: m.round + 1, Seconds: int(time.Until(m.deadline) / time.Second)})
+	}
+	return events
+}
+
+// nextRound moves on to the next card, or ends the match after the last one.
+// It does nothing while the current round is still being played.
+func (m *match) nextRound(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.over || m.round >= len(m.cards) || m.speed {
+		// A speed round deals its cards by itself, see dealNext
+		m.mu.Unlock()
+		return nil
+	}
+	m.round++
+	m.closeSessionsLocked()
+	done := m.round == len(m.cards)
+	score, results := m.score, m.results
+	m.mu.Unlock()
+
+	if !done {
+		return m.startRound(ctx)
+	}
+	m.publishMatchOver(score, len(m.cards), results)
+	return nil
+}
+
+// dealNext deals the next card of a speed round right after the verdict of
+// the given round, over the same hub, with fresh Gemini Live sessions so that
+// the AI player starts over and the judge knows the new proscribed words.
+// The match is over when the time is up, or when the deck runs out.
+func (m *match) dealNext(ctx context.Context, round int, reason string) {
+	m.mu.Lock()
+	if m.finished || round != m.round {
+		m.mu.Unlock()
+		return
+	}
+	m.closeSessionsLocked()
+	if m.timeUp || reason == reasonTimeUp || reason == reasonAbandoned || round+1 == len(m.cards) {
+		m.finishLocked()
+		return
+	}
+	m.round++
+	m.started = false
+	m.over = false
+	m.mu.Unlock()
+
+	if err := m.startRound(ctx); err != nil {
+		log.Printf("Game %s: %v", m.id, err)
+		m.mu.Lock()
+		m.finishLocked()
+	}
+}
+
+// finishLocked ends the speed round, and unlocks m.mu.
+func (m *match) finishLocked() {
+	m.finished = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	score, results := m.score, m.results
+	m.mu.Unlock()
+	m.publishMatchOver(score, len(results), results)
+}
+
+// publishMatchOver tells the final score of the match.
+func (m *match) publishMatchOver(score, rounds int, results []roundResult) {
+	log.Printf("Game %s over, score %d/%d", m.id, score, rounds)
+	m.hub.publish(gameEvent{
+		Event:   "matchOver",
+		Game:    m.id,
+		Rounds:  rounds,
+		Score:   score,
+		Results: results,
+	})
+}
+
+// startTimer starts the countdown of the current round.
+// In reverse mode, it also tells the AI to start describing.
+func (m *match) startTimer() {
+	m.mu.Lock()
+	if m.over || m.timer != nil || m.round >= len(m.cards) {
+		m.mu.Unlock()
+		return
+	}
+	round := m.round
+	word := m.cards[round].Word
+	duration := roundDuration
+	if m.speed {
+		duration = speedDuration
+	}
+	m.timer = time.AfterFunc(duration, func() {
+		if m.speed {
+			m.speedTimeUp()
+			return
+		}
+		m.endRound(round, roundResult{Word: word, Reason: reasonTimeUp})
+	})
+	m.deadline = time.Now().Add(duration)
+	session := m.session
+	m.mu.Unlock()
+
+	if m.reverse && session != nil {
+		err := session.SendClientContent(genai.LiveClientContentInput{
+			Turns: []*genai.Content{genai.NewContentFromText(describerKickoff, genai.RoleUser)},
+		})
+		if err != nil {
+			log.Printf("Game %s: kick off describer: %v", m.id, err)
+		}
+	}
+
+	m.hub.publish(gameEvent{
+		Event:   "timer",
+		Round:   round + 1,
+		Seconds: int(duration / time.Second),
+	})
+}
+
+// speedTimeUp ends the speed round when its clock runs out. The time is up
+// for the current card, whichever it is; if it already has a verdict, the
+// flag makes sure that the next card is not dealt.
+func (m *match) speedTimeUp() {
+	m.mu.Lock()
+	m.timeUp = true
+	round, word := m.round, m.cards[m.round].Word
+	playing := m.started && !m.over
+	m.mu.Unlock()
+	if !playing {
+		// The time ran out between two cards
+		m.dealNext(context.Background(), round, reasonTimeUp)
+		return
+	}
+	m.endRound(round, roundResult{Word: word, Reason: reasonTimeUp})
+}
+
+// endRound records the verdict of the given round, unless it already has one.
+// It reports whether it recorded it.
+func (m *match) endRound(round int, result roundResult) bool {
+	m.mu.Lock()
+	if round != m.round || m.over {
+		m.mu.Unlock()
+		return false
+	}
+	m.over = true
+	if m.timer != nil && !m.speed {
+		m.timer.Stop()
+	}
+	if result.Won && !m.deadline.IsZero() && !m.speed {
+		result.Seconds = (roundDuration - time.Until(m.deadline)).Seconds()
+	}
+	m.results = append(m.results, result)
+	if result.Won {
+		m.score++
+	}
+	score := m.score
+	rec := roundRecord{
+		Time:    time.Now(),
+		Lang:    m.lang,
+		Card:    m.cards[round].ID,
+		Player:  m.player,
+		Won:     result.Won,
+		Reason:  result.Reason,
+		Seconds: result.Seconds,
+	}
+	report := textgame.RoundReport{
+		Card:         &textgame.Card{Card: m.cards[round], LanguageName: languageNames[m.lang]},
+		HumanGuesses: m.reverse,
+		Transcript:   m.transcript,
+		Outcome:      outcome(result),
+	}
+	m.mu.Unlock()
+
+	m.vg.archive.add(m.id, round, archivedRound{report: report, result: result})
+
+	// In a speed round, the last card is cut short by the time: it doesn't tell the rating
+	if !m.reverse && !m.speed && m.deckID == "" && result.Reason != reasonAbandoned {
+		m.vg.history.record(rec)
+	}
+
+	log.Printf("Game %s round %d: %s %q", m.id, round+1, result.Reason, result.Said)
+	m.hub.publish(gameEvent{
+		Event:  "verdict",
+		Round:  round + 1,
+		Result: &result,
+		Score:  score,
+	})
+	if m.onVerdict != nil {
+		m.onVerdict(result)
+	}
+	if m.speed {
+		m.dealNext(context.Background(), round, result.Reason)
+	}
+	return true
+}
+
+// setPlayer sets the ID of the describer of the next rounds, in a room.
+func (m *match) setPlayer(player string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.player = player
+}
+
+// done tells if all the cards of the match have been played.
+func (m *match) done() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.finished || m.round >= len(m.cards)
+}
+
+// recentSpeech returns the current round and its card, and the last n words
+// of the describer, if the round is being played.
+func (m *match) recentSpeech(n int) (round int, card deck.Card, words string, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.playingLocked(m.round) {
+		return 0, deck.Card{}, "", false
+	}
+	speech := strings.Fields(m.humanSpeech.String())
+	speech = speech[max(0, len(speech)-n):]
+	return m.round, m.cards[m.round], strings.Join(speech, " "), true
+}
+
+// playing tells if the given round is being played.
+func (m *match) playing(round int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.playingLocked(round)
+}
+
+func (m *match) playingLocked(round int) bool {
+	return m.started && !m.over && round == m.round && round < len(m.cards)
+}
+
+// abandon ends the current round, if it is still being played.
+func (m *match) abandon() {
+	m.mu.Lock()
+	round := m.round
+	if round >= len(m.cards) {
+		m.mu.Unlock()
+		return
+	}
+	word := m.cards[round].Word
+	m.mu.Unlock()
+	m.endRound(round, roundResult{Word: word, Reason: reasonAbandoned})
+}
+
+// observe watches the transcriptions of the AI player session, to find out if
+// the describer said a proscribed word, or if the guesser found the word.
+func (m *match) observe(round int, sc *genai.LiveServerContent) {
+	m.mu.Lock()
+	if round != m.round || m.over {
+		m.mu.Unlock()
+		return
+	}
+	card := m.cards[round]
+	sessionJudge := m.sessionJudge
+	var transcripts []gameEvent
+	var result *roundResult
+	var aiSaid string
+	if t := sc.InputTranscription; t != nil && t.Text != "" {
+		transcripts = append(transcripts, gameEvent{Event: "transcript", Round: round + 1, Speaker: "human", Text: t.Text})
+		m.humanSpeech.WriteString(t.Text + " ")
+		m.humanTurn.WriteString(t.Text + " ")
+		m.addTranscriptLocked("human", t.Text+" ")
+		if m.reverse {
+			if firstContained(m.humanSpeech.String(), card.Answers()) != "" {
+				result = &roundResult{Word: card.Word, Won: true, Reason: reasonGuessed}
+			}
+		} else if word := firstContained(m.humanSpeech.String(), card.Proscribed()); word != "" {
+			result = &roundResult{Word: card.Word, Reason: reasonForbidden, Said: word}
+		} else if v := textgame.CheckRules(m.rules, m.humanTurn.String()); v != nil {
+			result = &roundResult{Word: card.Word, Reason: reasonRule, Said: v.Fragment, Violation: v}
+		}
+	}
+	if t := sc.OutputTranscription; result == nil && t != nil && t.Text != "" {
+		transcripts = append(transcripts, gameEvent{Event: "transcript", Round: round + 1, Speaker: "ai", Text: t.Text})
+		m.modelSpeech.WriteString(t.Text)
+		m.humanTurn.Reset()
+		m.addTranscriptLocked("ai", t.Text)
+		if m.reverse {
+			aiSaid = t.Text
+			if word := firstContained(m.modelSpeech.String(), card.Proscribed()); word != "" {
+				result = &roundResult{Word: card.Word, Reason: reasonAIForbidden, Said: word}
+			}
+		} else if firstContained(m.modelSpeech.String(), card.Answers()) != "" {
+			result = &roundResult{Word: card.Word, Won: true, Reason: reasonGuessed}
+		}
+	}
+	m.mu.Unlock()
+
+	if aiSaid != "" && sessionJudge != nil {
+		// In reverse mode, the judge polices the AI describer
+		sessionJudge.SendRealtimeInput(genai.LiveRealtimeInput{Text: aiSaid})
+	}
+	for _, transcript := range transcripts {
+		m.hub.publish(transcript)
+	}
+	if result != nil {
+		m.endRound(round, *result)
+	}
+}
+
+// addTranscriptLocked adds the transcribed text of the speaker to the
+// transcript of the round, continuing their current line if any.
+func (m *match) addTranscriptLocked(speaker, text string) {
+	if n := len(m.transcript); n > 0 && m.transcript[n-1].Speaker == speaker {
+		m.transcript[n-1].Text += text
+		return
+	}
+	m.transcript = append(m.transcript, textgame.Line{Speaker: speaker, Text: text})
+}
+
+// containsWord tells if speech contains word, ignoring case.
+func containsWord(speech, word string) bool {
+	return strings.Contains(strings.ToLower(speech), strings.ToLower(word))
+}
+
+// firstContained returns the first of words contained in speech, or "".
+func firstContained(speech string, words []string) string {
+	for _, word := range words {
+		if containsWord(speech, word) {
+			return word
+		}
+	}
+	return ""
+}
+
+// sendRealtimeInput forwards the human speech to the current Live sessions.
+// In reverse mode, the judge doesn't listen to the human.
+func (m *match) sendRealtimeInput(input genai.LiveRealtimeInput) {
+	m.mu.Lock()
+	session, sessionJudge := m.session, m.sessionJudge
+	m.mu.Unlock()
+	if session != nil {
+		session.SendRealtimeInput(input)
+	}
+	if sessionJudge != nil && !m.reverse {
+		sessionJudge.SendRealtimeInput(input)
+	}
+}
+
+func (m *match) closeSessions() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.closeSessionsLocked()
+}
+
+func (m *match) closeSessionsLocked() {
+	if m.session != nil {
+		m.session.Close()
+		m.session = nil
+	}
+	if m.sessionJudge != nil {
+		m.sessionJudge.Close()
+		m.sessionJudge = nil
+	}
+}
+
+func (m *match) playerLoop(round int, session *genai.Session) {
+	// AI player Loop:
+	// Receive audio data from the Gemini Live session.
+	// Forward it to the player browser, via the hub.
+	for {
+		message, err := session.Receive()
+		if err != nil {
+			log.Println("player model deconnected: ", err)
+			return
+		}
+		m.hub.publish(message)
+		if message.ServerContent != nil {
+			m.observe(round, message.ServerContent)
+		}
+	}
+}
+
+func (m *match) judgeLoop(round int, sessionJudge *genai.Session) {
+	// Judge Loop:
+	// Receive audio and transcript data from the Gemini Live session.
+	// Tell the browser what the judge says.
+	for {
+		message, err := sessionJudge.Receive()
+		if err != nil {
+			log.Println("judge deconnected: ", err)
+			return
+		}
+		sc := message.ServerContent
+		if sc != nil {
+			ot := sc.OutputTranscription
+			if ot != nil {
+				log.Printf("Game %s Judge says %q", m.id, ot.Text)
+				m.hub.publish(gameEvent{Event: "judge", Round: round + 1, Text: ot.Text})
+			}
+		}
+	}
+}
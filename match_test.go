@@ -0,0 +1,43 @@
+package verboten
+
+import (
+	"testing"
+
+	"github.com/Deleplace/verboten/deck"
+)
+
+func TestSpeedTimeUp(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// betweenCards fires the timer after the verdict of the first card,
+		// before the next card is dealt.
+		betweenCards bool
+		want         []roundResult
+	}{
+		{"while playing", false, []roundResult{{Word: "Apple", Reason: reasonTimeUp}}},
+		{"between cards", true, []roundResult{{Word: "Apple", Won: true, Reason: reasonGuessed}}},
+	} {
+		m := &match{
+			vg:      &VerbotenGameServer{archive: newGameArchive(), history: newHistory()},
+			id:      "test",
+			lang:    "en",
+			cards:   []deck.Card{{ID: "apple", Word: "Apple"}, {ID: "pencil", Word: "Pencil"}},
+			hub:     newHub(),
+			speed:   true,
+			started: true,
+		}
+		if tc.betweenCards {
+			m.onVerdict = func(roundResult) { m.speedTimeUp() }
+			m.endRound(0, roundResult{Word: "Apple", Won: true, Reason: reasonGuessed})
+		} else {
+			m.speedTimeUp()
+		}
+
+		if !m.finished || m.round != 0 {
+			t.Errorf("%s: finished %t at round %d, want finished at round 0", tc.name, m.finished, m.round)
+		}
+		if len(m.results) != len(tc.want) || m.results[0] != tc.want[0] {
+			t.Errorf("%s: results %+v, want %+v", tc.name, m.results, tc.want)
+		}
+	}
+}
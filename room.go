@@ -0,0 +1,489 @@
+package verboten
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/Deleplace/verboten/deck"
+	"github.com/Deleplace/verboten/textgame"
+)
+
+// roomTTL is how long a room stays open without any activity.
+const roomTTL = time.Hour
+
+// defaultTeams are the teams proposed to the members of a room.
+var defaultTeams = []string{"A", "B"}
+
+const (
+	// buzzWindow is the number of last words of the describer which the
+	// judge hears when a member buzzes.
+	buzzWindow = 30
+	// buzzPenalty is what a wrong buzz costs to the team of the buzzer.
+	buzzPenalty = 1
+)
+
+// roomRegistry holds the open rooms, by room code.
+type roomRegistry struct {
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+// room is a game where several humans take turns describing the cards, in
+// front of all the other members. Each turn is one round of the room's match.
+type room struct {
+	code   string
+	lang   string
+	deckID string // empty for the built-in deck
+	hub    *hub
+	match  *match
+
+	// mu protects the fields below.
+	mu         sync.Mutex
+	members    []*member // in turn order
+	turn       int       // index in members of the next describer
+	describer  *member   // nil between turns
+	started    bool      // the first card has been dealt
+	ruling     bool      // the judge is ruling on a buzz
+	scores     map[string]int
+	lastActive time.Time
+}
+
+type member struct {
+	Name   string `json:"name"`
+	Team   string `json:"team"`
+	player string // anonymous player ID, for the game history
+}
+
+// roomState is what all the members of a room see about it.
+type roomState struct {
+	Code      string         `json:"code"`
+	Lang      string         `json:"lang"`
+	Members   []*member      `json:"members"`
+	Describer string         `json:"describer"` // current or next describer
+	Playing   bool           `json:"playing"`
+	Scores    map[string]int `json:"scores"`
+}
+
+// buzzRuling is what all the members of a room see about a buzz: first
+// pending, then upheld or not.
+type buzzRuling struct {
+	Buzzer  *member `json:"buzzer"`
+	Pending bool    `json:"pending,omitempty"`
+	Upheld  bool    `json:"upheld,omitempty"`
+	// Said is what the describer said that was too close to a proscribed word.
+	Said string `json:"said,omitempty"`
+	// Error means the judge could not rule, and the buzz doesn't count.
+	Error bool `json:"error,omitempty"`
+}
+
+func newRoomRegistry() *roomRegistry {
+	return &roomRegistry{
+		rooms: make(map[string]*room),
+	}
+}
+
+// create opens a new room in the given language, with the shuffled cards of
+// the deck deckID selected by the filter, and the extra rules of the
+// descriptions. The room code is also its game ID, for spectators.
+func (reg *roomRegistry) create(vg *VerbotenGameServer, lang, prompt, deckID string, filter deck.Filter, rules []textgame.Rule) (*room, error) {
+	words, err := vg.deckFor(deckID, filter)
+	if err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	cards, err := words.Draw(lang, len(words[lang]), rng)
+	if err != nil {
+		return nil, err
+	}
+
+	rm := &room{
+		lang:       lang,
+		deckID:     deckID,
+		hub:        newHub(),
+		scores:     make(map[string]int),
+		lastActive: time.Now(),
+	}
+	for _, team := range defaultTeams {
+		rm.scores[team] = 0
+	}
+	rm.match = &match{
+		vg:        vg,
+		lang:      lang,
+		prompt:    prompt,
+		deckID:    deckID,
+		cards:     cards,
+		hub:       rm.hub,
+		rules:     rules,
+		onVerdict: rm.verdict,
+	}
+	rm.code = vg.games.add(rm)
+	rm.match.id = rm.code
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.rooms[rm.code] = rm
+	return rm, nil
+}
+
+func (reg *roomRegistry) get(code string) *room {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.rooms[code]
+}
+
+// expireLoop closes the rooms which have been idle for longer than roomTTL.
+func (reg *roomRegistry) expireLoop(games *gameRegistry) {
+	for range time.Tick(time.Minute) {
+		var expired []*room
+		reg.mu.Lock()
+		for code, rm := range reg.rooms {
+			if rm.idleSince() > roomTTL {
+				delete(reg.rooms, code)
+				expired = append(expired, rm)
+			}
+		}
+		reg.mu.Unlock()
+
+		for _, rm := range expired {
+			log.Printf("Room %s expired", rm.code)
+			games.remove(rm.code)
+			rm.match.closeSessions()
+			rm.hub.close()
+		}
+	}
+}
+
+func (rm *room) gameHub() *hub {
+	return rm.hub
+}
+
+// snapshot returns the state of the room, and of its current round.
+func (rm *room) snapshot() []gameEvent {
+	return append([]gameEvent{{Event: "room", Room: rm.state()}}, rm.match.snapshot()...)
+}
+
+func (rm *room) idleSince() time.Duration {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return time.Since(rm.lastActive)
+}
+
+// join adds a member at the end of the turn order. The name must be unique in the room.
+func (rm *room) join(name, team, player string) (*member, bool) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	for _, m := range rm.members {
+		if m.Name == name {
+			return nil, false
+		}
+	}
+	if team == "" {
+		team = rm.smallestTeamLocked()
+	}
+	if _, ok := rm.scores[team]; !ok {
+		rm.scores[team] = 0
+	}
+	m := &member{Name: name, Team: team, player: player}
+	rm.members = append(rm.members, m)
+	rm.lastActive = time.Now()
+	return m, true
+}
+
+func (rm *room) smallestTeamLocked() string {
+	best, bestSize := defaultTeams[0], len(rm.members)+1
+	for _, team := range defaultTeams {
+		size := 0
+		for _, m := range rm.members {
+			if m.Team == team {
+				size++
+			}
+		}
+		if size < bestSize {
+			best, bestSize = team, size
+		}
+	}
+	return best
+}
+
+// leave removes a member. If they were describing, their turn is lost.
+func (rm *room) leave(m *member) {
+	rm.mu.Lock()
+	i := slices.Index(rm.members, m)
+	if i < 0 {
+		rm.mu.Unlock()
+		return
+	}
+	rm.members = slices.Delete(rm.members, i, i+1)
+	if i < rm.turn {
+		rm.turn--
+	}
+	if rm.turn >= len(rm.members) {
+		rm.turn = 0
+	}
+	wasDescribing := rm.describer == m
+	rm.mu.Unlock()
+
+	if wasDescribing {
+		rm.match.abandon()
+	}
+	rm.publishState()
+}
+
+// startTurn deals the next card, if m is the next describer and no turn is
+// being played.
+func (rm *room) startTurn(ctx context.Context, m *member) error {
+	if rm.match.done() {
+		return nil
+	}
+	rm.mu.Lock()
+	if rm.describer != nil || len(rm.members) == 0 || rm.members[rm.turn] != m {
+		rm.mu.Unlock()
+		return nil
+	}
+	rm.describer = m
+	started := rm.started
+	rm.started = true
+	rm.lastActive = time.Now()
+	rm.mu.Unlock()
+
+	rm.publishState()
+	rm.match.setPlayer(m.player)
+	var err error
+	if started {
+		err = rm.match.nextRound(ctx)
+	} else {
+		err = rm.match.startRound(ctx)
+	}
+	if err != nil || rm.match.done() {
+		// No card was dealt
+		rm.mu.Lock()
+		rm.describer = nil
+		rm.mu.Unlock()
+		rm.publishState()
+	}
+	return err
+}
+
+// isDescribing tells if m is the member currently describing a card.
+func (rm *room) isDescribing(m *member) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return rm.describer == m
+}
+
+// verdict scores the turn for the describer's team, and passes the turn on.
+func (rm *room) verdict(result roundResult) {
+	rm.mu.Lock()
+	if result.Buzzer != nil {
+		rm.scores[result.Buzzer.Team]++
+	}
+	if d := rm.describer; d != nil {
+		if result.Won {
+			rm.scores[d.Team]++
+		}
+		if rm.turn < len(rm.members) && rm.members[rm.turn] == d {
+			rm.turn = (rm.turn + 1) % len(rm.members)
+		}
+	}
+	rm.describer = nil
+	rm.lastActive = time.Now()
+	rm.mu.Unlock()
+
+	rm.publishState()
+}
+
+// buzz lets m, a member of an opposing team, challenge the describer: the
+// judge rules on the last words of the description. A correct buzz ends the
+// turn and scores for the team of the buzzer, a wrong one costs them a point.
+// Only one buzz is judged at a time.
+func (rm *room) buzz(ctx context.Context, m *member) {
+	rm.mu.Lock()
+	d := rm.describer
+	if d == nil || d.Team == m.Team || rm.ruling {
+		rm.mu.Unlock()
+		return
+	}
+	rm.ruling = true
+	rm.lastActive = time.Now()
+	rm.mu.Unlock()
+	defer func() {
+		rm.mu.Lock()
+		rm.ruling = false
+		rm.mu.Unlock()
+	}()
+
+	round, card, words, ok := rm.match.recentSpeech(buzzWindow)
+	if !ok {
+		return
+	}
+	log.Printf("Room %s: %s buzzed on %q", rm.code, m.Name, words)
+	rm.hub.publish(gameEvent{Event: "buzz", Round: round + 1, Buzz: &buzzRuling{Buzzer: m, Pending: true}})
+
+	gameWord := &textgame.Card{Card: card, LanguageName: languageNames[rm.lang]}
+	verdict, err := rm.match.vg.judge.SaidForbidden(ctx, gameWord, words)
+	if err != nil {
+		log.Printf("Room %s: %v", rm.code, err)
+		rm.hub.publish(gameEvent{Event: "buzz", Round: round + 1, Buzz: &buzzRuling{Buzzer: m, Error: true}})
+		return
+	}
+	if !rm.match.playing(round) {
+		// The round ended while the judge was ruling
+		return
+	}
+	if verdict.Lost {
+		if rm.match.endRound(round, roundResult{Word: card.Word, Reason: reasonBuzzed, Said: verdict.Fragment, Buzzer: m}) {
+			rm.hub.publish(gameEvent{Event: "buzz", Round: round + 1, Buzz: &buzzRuling{Buzzer: m, Upheld: true, Said: verdict.Fragment}})
+		}
+		return
+	}
+	rm.hub.publish(gameEvent{Event: "buzz", Round: round + 1, Buzz: &buzzRuling{Buzzer: m}})
+	rm.mu.Lock()
+	rm.scores[m.Team] -= buzzPenalty
+	rm.mu.Unlock()
+	rm.publishState()
+}
+
+func (rm *room) publishState() {
+	rm.hub.publish(gameEvent{Event: "room", Room: rm.state()})
+}
+
+func (rm *room) state() *roomState {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	state := &roomState{
+		Code:    rm.code,
+		Lang:    rm.lang,
+		Members: append([]*member{}, rm.members...),
+		Playing: rm.describer != nil,
+		Scores:  make(map[string]int, len(rm.scores)),
+	}
+	if len(rm.members) > 0 {
+		state.Describer = rm.members[rm.turn].Name
+	}
+	if rm.describer != nil {
+		state.Describer = rm.describer.Name
+	}
+	for team, score := range rm.scores {
+		state.Scores[team] = score
+	}
+	return state
+}
+
+// createRoom opens a room for the language given in the "lang" form value,
+// with the optional custom deck of the "deck" form value, the optional
+// "tags" and "difficulty" filters and the optional extra "rules", and
+// responds with its code.
+func (vg *VerbotenGameServer) createRoom(w http.ResponseWriter, r *http.Request) {
+	lang := r.FormValue("lang")
+	prompt, ok := guesserPrompts[lang]
+	if !ok {
+		http.Error(w, "unsupported language", http.StatusBadRequest)
+		return
+	}
+	filter, err := requestFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rules, err := textgame.ParseRules(r.FormValue("rules"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rm, err := vg.rooms.create(vg, lang, prompt, r.FormValue("deck"), filter, rules)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Room %s created in %s", rm.code, lang)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"code": rm.code,
+		"url":  "/room/" + rm.code,
+	})
+}
+
+// serveRoom serves the game page, in room mode.
+func (vg *VerbotenGameServer) serveRoom(w http.ResponseWriter, r *http.Request) {
+	rm := vg.rooms.get(r.PathValue("code"))
+	if rm == nil {
+		http.NotFound(w, r)
+		return
+	}
+	vg.renderGame(w, gamePage{Room: rm.code, Lang: rm.lang})
+}
+
+// roomSocket is the WebSocket of one member of a room. All the members
+// receive the events of the room; only the current describer's audio is
+// forwarded to the Live sessions.
+func (vg *VerbotenGameServer) roomSocket(w http.ResponseWriter, r *http.Request) {
+	rm := vg.rooms.get(r.PathValue("code"))
+	if rm == nil {
+		http.NotFound(w, r)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "missing name", http.StatusBadRequest)
+		return
+	}
+
+	c, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("upgrade error: ", err)
+		return
+	}
+	defer c.Close()
+
+	m, ok := rm.join(name, r.URL.Query().Get("team"), playerID(r))
+	if !ok {
+		log.Printf("Room %s: name %q already taken", rm.code, name)
+		c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "name already taken"))
+		return
+	}
+	defer rm.leave(m)
+	defer rm.hub.leave(rm.hub.join(c))
+	log.Printf("Room %s: %s joined team %s", rm.code, m.Name, m.Team)
+	rm.publishState()
+
+	ctx := context.Background()
+	for {
+		_, message, err := c.ReadMessage()
+		if err != nil {
+			log.Println("read from client error: ", err)
+			break
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			log.Println("unmarshal message error ", string(message), err)
+			break
+		}
+		switch {
+		case msg.NextRound:
+			err = rm.startTurn(ctx, m)
+		case msg.Buzz:
+			rm.buzz(ctx, m)
+		case !rm.isDescribing(m):
+			// Only the describer speaks to the AI
+		case msg.Ready:
+			rm.match.startTimer()
+		default:
+			rm.match.sendRealtimeInput(msg.LiveRealtimeInput)
+		}
+		if err != nil {
+			log.Printf("Room %s: %v", rm.code, err)
+			break
+		}
+	}
+}
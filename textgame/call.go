@@ -0,0 +1,205 @@
+package textgame
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// ErrEmptyResponse is the error of a model response without any text.
+var ErrEmptyResponse = errors.New("empty response from model")
+
+// BlockedError is the error of a model response blocked by the safety
+// filters, or cut short for another reason than the end of the text.
+type BlockedError struct {
+	// Reason is the block reason of the prompt, or the finish reason of the candidate.
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return "response blocked by model: " + e.Reason
+}
+
+// CallPolicy bounds the model calls: each attempt has a deadline, and the
+// transient errors are retried after an exponential backoff with jitter.
+type CallPolicy struct {
+	// Timeout is the deadline of each attempt.
+	Timeout time.Duration
+	// Retries is the maximum number of attempts after the first one.
+	Retries int
+	// Backoff is the delay before the first retry. It doubles at each retry.
+	Backoff time.Duration
+}
+
+// DefaultPolicy is the CallPolicy of Generate.
+var DefaultPolicy = CallPolicy{
+	Timeout: 30 * time.Second,
+	Retries: 2,
+	Backoff: 500 * time.Millisecond,
+}
+
+// Generate calls the model with the DefaultPolicy. See CallPolicy.Generate.
+func Generate(ctx context.Context, client *genai.Client, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	return DefaultPolicy.Generate(ctx, client, model, contents, config)
+}
+
+// Generate calls the model, retrying the transient errors, and checks that
+// the response has some text. It returns ErrEmptyResponse or a *BlockedError
+// for the responses without text.
+func (p CallPolicy) Generate(ctx context.Context, client *genai.Client, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	backoff := p.Backoff
+	for attempt := 0; ; attempt++ {
+		res, err := p.attempt(ctx, client, model, contents, config)
+		if err == nil {
+			return res, checkResponse(res)
+		}
+		if attempt >= p.Retries || ctx.Err() != nil || !isTransient(err) {
+			return nil, err
+		}
+		if err := sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// GenerateStream calls the model with the DefaultPolicy, in streaming mode.
+// See CallPolicy.GenerateStream.
+func GenerateStream(ctx context.Context, client *genai.Client, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return DefaultPolicy.GenerateStream(ctx, client, model, contents, config)
+}
+
+// GenerateStream calls the model in streaming mode, and yields the chunks of
+// the response. The transient errors are retried only until the first chunk.
+// The Timeout applies to the whole response. A response without any text
+// ends with ErrEmptyResponse or a *BlockedError.
+func (p CallPolicy) GenerateStream(ctx context.Context, client *genai.Client, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		backoff := p.Backoff
+		for attempt := 0; ; attempt++ {
+			started, stopped, err := p.attemptStream(ctx, client, model, contents, config, yield)
+			if stopped || err == nil {
+				return
+			}
+			if started || attempt >= p.Retries || ctx.Err() != nil || !isTransient(err) {
+				yield(nil, err)
+				return
+			}
+			if err := sleep(ctx, backoff); err != nil {
+				yield(nil, err)
+				return
+			}
+			backoff *= 2
+		}
+	}
+}
+
+// attemptStream yields the chunks of one streaming call. It tells if any
+// chunk was yielded, and if the consumer stopped the iteration.
+func (p CallPolicy) attemptStream(ctx context.Context, client *genai.Client, model string, contents []*genai.Content, config *genai.GenerateContentConfig, yield func(*genai.GenerateContentResponse, error) bool) (started, stopped bool, err error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	var last *genai.GenerateContentResponse
+	for chunk, err := range client.Models.GenerateContentStream(ctx, model, contents, config) {
+		if err != nil {
+			return started, false, err
+		}
+		last = chunk
+		if checkResponse(chunk) != nil {
+			// No text in this chunk, e.g. only the finish reason
+			continue
+		}
+		started = true
+		if !yield(chunk, nil) {
+			return started, true, nil
+		}
+	}
+	if !started {
+		if last == nil {
+			return false, false, ErrEmptyResponse
+		}
+		if err := checkResponse(last); err != nil {
+			return false, false, err
+		}
+	}
+	return started, false, nil
+}
+
+// sleep waits for a random delay up to d, unless ctx is done first. The full
+// jitter keeps the concurrent games from retrying in sync.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(rand.N(d + 1)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p CallPolicy) attempt(ctx context.Context, client *genai.Client, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	return client.Models.GenerateContent(ctx, model, contents, config)
+}
+
+// isTransient tells if a failed call is worth retrying.
+func isTransient(err error) bool {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// The attempt timed out
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// checkResponse returns an error if the response has no text.
+func checkResponse(res *genai.GenerateContentResponse) error {
+	if len(res.Candidates) == 0 {
+		if res.PromptFeedback != nil && res.PromptFeedback.BlockReason != "" {
+			return &BlockedError{Reason: string(res.PromptFeedback.BlockReason)}
+		}
+		return ErrEmptyResponse
+	}
+	c := res.Candidates[0]
+	if c.Content == nil || len(c.Content.Parts) == 0 {
+		switch c.FinishReason {
+		case "", genai.FinishReasonStop, genai.FinishReasonUnspecified:
+			return ErrEmptyResponse
+		}
+		return &BlockedError{Reason: string(c.FinishReason)}
+	}
+	return nil
+}
+
+// TextOf returns the text of the first candidate of a model response.
+func TextOf(res *genai.GenerateContentResponse) (string, error) {
+	if err := checkResponse(res); err != nil {
+		return "", err
+	}
+	return res.Candidates[0].Content.Parts[0].Text, nil
+}
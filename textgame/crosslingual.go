@@ -0,0 +1,105 @@
+package textgame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/Deleplace/verboten/deck"
+)
+
+// CrossLingualCard is the card of the cross-lingual mode, where the player
+// sees the native card, but describes it in the learning language: the
+// target card. The words of both cards are proscribed, and the answers of
+// both cards win.
+func CrossLingualCard(target, native deck.Card, languageName string) Card {
+	c := target
+	c.Accepted = slices.Concat(target.Accepted, native.Answers())
+	c.Forbidden = slices.Concat(target.Forbidden, native.Forbidden)
+	return Card{Card: c, LanguageName: languageName}
+}
+
+// Correction is a mistake in a description, and how to fix it.
+type Correction struct {
+	Original    string `json:"original"`
+	Corrected   string `json:"corrected"`
+	Explanation string `json:"explanation"`
+}
+
+// LanguageFeedback is the feedback of the model on the descriptions of a
+// language learner.
+type LanguageFeedback struct {
+	Summary     string       `json:"summary"`
+	Corrections []Correction `json:"corrections"`
+	// Vocabulary are useful words for the descriptions, with their translation.
+	Vocabulary []string `json:"vocabulary"`
+}
+
+// FeedbackOnLanguage asks the model for vocabulary and grammar feedback on
+// the descriptions, written in the learning language by a speaker of the
+// native language. The feedback is in the native language.
+func FeedbackOnLanguage(ctx context.Context, client *genai.Client, model, learningLanguage, nativeLanguage string, descriptions []string) (LanguageFeedback, error) {
+	prompt := fmt.Sprintf(`
+		A %s speaker learning %s wrote these descriptions of words, in a word guessing game:
+
+		%s
+
+		In "summary", give a short and encouraging assessment of their %s.
+		In "corrections", list the vocabulary and grammar mistakes, each with its correction and
+		a short explanation. Ignore the missing punctuation and capitals.
+		In "vocabulary", suggest a few %s words which would have helped, each followed by its
+		translation in parentheses.
+		Write the summary and the explanations in %s.
+		`, nativeLanguage, learningLanguage, "- "+strings.Join(descriptions, "\n\t\t- "),
+		learningLanguage, learningLanguage, nativeLanguage)
+
+	config := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseJsonSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"summary": {
+					Type:        genai.TypeString,
+					Description: "A short assessment.",
+				},
+				"corrections": {
+					Type:        genai.TypeArray,
+					Description: "The mistakes and their corrections.",
+					Items: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"original":    {Type: genai.TypeString},
+							"corrected":   {Type: genai.TypeString},
+							"explanation": {Type: genai.TypeString},
+						},
+						Required: []string{"original", "corrected", "explanation"},
+					},
+				},
+				"vocabulary": {
+					Type:        genai.TypeArray,
+					Description: "Useful words, with their translation.",
+					Items:       &genai.Schema{Type: genai.TypeString},
+				},
+			},
+			Required: []string{"summary", "corrections", "vocabulary"},
+		},
+	}
+
+	resp, err := Generate(ctx, client, model, genai.Text(prompt), config)
+	if err != nil {
+		return LanguageFeedback{}, err
+	}
+	text, err := TextOf(resp)
+	if err != nil {
+		return LanguageFeedback{}, err
+	}
+	var f LanguageFeedback
+	if err := json.Unmarshal([]byte(text), &f); err != nil {
+		return LanguageFeedback{}, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,107 @@
+package textgame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Line is a line of the transcript of a round.
+type Line struct {
+	// Speaker is "human" or "ai".
+	Speaker string `json:"speaker"`
+	Text    string `json:"text"`
+}
+
+// RoundReport is what happened in a round, for its debrief.
+type RoundReport struct {
+	Card *Card
+	// HumanGuesses is true in reverse mode, when the AI describes the card.
+	HumanGuesses bool
+	Transcript   []Line
+	// Outcome tells how the round ended, e.g. "won", or "lost: the describer
+	// said the proscribed word Sky".
+	Outcome string
+}
+
+// Debrief is the explanation of a round by the model, after the game.
+type Debrief struct {
+	// Explanation is why the guesser guessed what it did.
+	Explanation string `json:"explanation"`
+	// HelpfulPhrases are the parts of the descriptions which helped the most.
+	HelpfulPhrases []string `json:"helpfulPhrases"`
+	// Tip is an advice to the human player for the next time.
+	Tip string `json:"tip"`
+}
+
+// DebriefRound asks the model to explain the round, in the language of the card.
+func DebriefRound(ctx context.Context, client *genai.Client, model string, r RoundReport) (Debrief, error) {
+	describer, guesser := "the human", "the AI"
+	if r.HumanGuesses {
+		describer, guesser = guesser, describer
+	}
+	var transcript strings.Builder
+	for _, line := range r.Transcript {
+		role := "Describer"
+		if (line.Speaker == "ai") != r.HumanGuesses {
+			role = "Guesser"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", role, strings.TrimSpace(line.Text))
+	}
+
+	prompt := fmt.Sprintf(`
+		A round of the Proscribed Words game is over.
+		The describer, %s, had to make the guesser, %s, find the word %q
+		without saying any of the proscribed words: %s.
+
+		Transcript of the round:
+		%s
+		Outcome: %s
+
+		In "explanation", explain in two or three sentences why the guesser guessed what it did.
+		In "helpfulPhrases", quote the parts of the descriptions which helped the most.
+		In "tip", give the human player one short tip for the next time.
+		Answer in %s, and talk to the human player directly.
+		`, describer, guesser, r.Card.Word, strings.Join(r.Card.Forbidden, ", "),
+		transcript.String(), r.Outcome, r.Card.LanguageName)
+
+	config := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseJsonSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"explanation": {
+					Type:        genai.TypeString,
+					Description: "Why the guesser guessed what it did.",
+				},
+				"helpfulPhrases": {
+					Type:        genai.TypeArray,
+					Description: "The most helpful parts of the descriptions.",
+					Items:       &genai.Schema{Type: genai.TypeString},
+				},
+				"tip": {
+					Type:        genai.TypeString,
+					Description: "A tip for the human player.",
+				},
+			},
+			Required: []string{"explanation", "helpfulPhrases", "tip"},
+		},
+	}
+
+	resp, err := Generate(ctx, client, model, genai.Text(prompt), config)
+	if err != nil {
+		return Debrief{}, err
+	}
+	text, err := TextOf(resp)
+	if err != nil {
+		return Debrief{}, err
+	}
+	var d Debrief
+	if err := json.Unmarshal([]byte(text), &d); err != nil {
+		return Debrief{}, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,96 @@
+package textgame
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"google.golang.org/genai"
+)
+
+// Candidate is a word the guesser considers.
+type Candidate struct {
+	Word string `json:"word"`
+	// Confidence is between 0 and 1.
+	Confidence float64 `json:"confidence"`
+}
+
+// rankedInstructions are added to the GuesserInstructions, by language code,
+// to get ranked candidates instead of a single word.
+var rankedInstructions = map[string]string{
+	"fr": `
+				Donne tes %d meilleurs mots candidats, du plus probable au moins probable,
+				avec pour chacun ta confiance entre 0 et 1.
+				`,
+	"en": `
+				Give your %d best candidate words, from the most to the least likely,
+				each with your confidence between 0 and 1.
+				`,
+}
+
+// RankedGuessConfig is the config of a guesser which answers with its n best
+// candidates, in the language of the given code. See ParseRankedGuess.
+func RankedGuessConfig(lang string, n int) *genai.GenerateContentConfig {
+	zero, one := 0.0, 1.0
+	minCount, maxCount := int64(1), int64(n)
+	return &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(
+			GuesserInstructions[lang]+fmt.Sprintf(rankedInstructions[lang], n), genai.RoleUser),
+		ResponseMIMEType: "application/json",
+		ResponseJsonSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"candidates": {
+					Type:        genai.TypeArray,
+					Description: "The best candidate words, the most likely first.",
+					MinItems:    &minCount,
+					MaxItems:    &maxCount,
+					Items: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"word": {
+								Type:        genai.TypeString,
+								Description: "A candidate word.",
+							},
+							"confidence": {
+								Type:        genai.TypeNumber,
+								Description: "How likely the word is the one described.",
+								Minimum:     &zero,
+								Maximum:     &one,
+							},
+						},
+						Required: []string{"word", "confidence"},
+					},
+				},
+			},
+			Required: []string{"candidates"},
+		},
+	}
+}
+
+// ParseRankedGuess returns the candidates of a response of the RankedGuessConfig,
+// by decreasing confidence. The first one is the official guess.
+func ParseRankedGuess(text string) ([]Candidate, error) {
+	var result struct {
+		Candidates []Candidate `json:"candidates"`
+	}
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	candidates := slices.DeleteFunc(result.Candidates, func(c Candidate) bool {
+		return c.Word == ""
+	})
+	if len(candidates) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	slices.SortStableFunc(candidates, func(a, b Candidate) int {
+		switch {
+		case a.Confidence > b.Confidence:
+			return -1
+		case a.Confidence < b.Confidence:
+			return 1
+		}
+		return 0
+	})
+	return candidates, nil
+}
@@ -0,0 +1,248 @@
+package textgame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Referee checks the descriptions for proscribed words.
+type Referee interface {
+	// SaidForbidden tells if the description said contains a proscribed word of the card.
+	SaidForbidden(ctx context.Context, card *Card, said string) (Verdict, error)
+}
+
+// Judge is the Referee which checks the descriptions with the help of a model.
+type Judge struct {
+	Client *genai.Client
+	// Model defaults to DefaultModel.
+	Model string
+}
+
+// The reasons why a fragment of a description matches a proscribed word.
+const (
+	ReasonExact       = "exact"
+	ReasonInflection  = "inflection"
+	ReasonTranslation = "translation"
+	ReasonMisspelling = "misspelling"
+	// ReasonSynonym never loses the game.
+	ReasonSynonym = "synonym"
+)
+
+// Reasons are the reasons of the findings, from the most to the least certain loss.
+var Reasons = []string{ReasonExact, ReasonInflection, ReasonMisspelling, ReasonTranslation, ReasonSynonym}
+
+// MinConfidence is the confidence from which a finding loses the game.
+const MinConfidence = 0.5
+
+// Finding is a fragment of a description which looks like a proscribed word.
+type Finding struct {
+	Fragment string `json:"fragment"`
+	// ForbiddenWord is the proscribed word of the card matched by Fragment.
+	ForbiddenWord string `json:"forbiddenWord"`
+	// Reason is one of Reasons.
+	Reason string `json:"reason"`
+	// Confidence is between 0 and 1.
+	Confidence float64 `json:"confidence"`
+}
+
+// loses tells if the finding loses the game.
+func (f Finding) loses() bool {
+	return f.Reason != ReasonSynonym && slices.Contains(Reasons, f.Reason) && f.Confidence >= MinConfidence
+}
+
+// Verdict is the decision of the judge about one description.
+type Verdict struct {
+	// Lost means the description used a proscribed word.
+	Lost bool `json:"lost"`
+	// Fragment, ForbiddenWord, Reason and Confidence are those of the finding
+	// which lost the game, or else of the most suspicious one, if any.
+	Fragment      string  `json:"fragment,omitempty"`
+	ForbiddenWord string  `json:"forbiddenWord,omitempty"`
+	Reason        string  `json:"reason,omitempty"`
+	Confidence    float64 `json:"confidence,omitempty"`
+	// Findings are all the fragments which looked like proscribed words.
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Suspicious means some fragments looked like proscribed words, without
+// losing the game.
+func (v Verdict) Suspicious() bool {
+	return !v.Lost && len(v.Findings) > 0
+}
+
+// verdictOf returns the verdict of the findings, ignoring those which
+// don't match a proscribed word of the card.
+func verdictOf(card *Card, findings []Finding) Verdict {
+	var v Verdict
+	proscribed := card.Proscribed()
+	for _, f := range findings {
+		i := slices.IndexFunc(proscribed, func(p string) bool {
+			return Normalize(p) == Normalize(f.ForbiddenWord)
+		})
+		if i < 0 || strings.TrimSpace(f.Fragment) == "" {
+			continue
+		}
+		f.ForbiddenWord = proscribed[i]
+		v.Findings = append(v.Findings, f)
+	}
+	// The losing findings first, then by confidence
+	best := -1
+	for i, f := range v.Findings {
+		if best < 0 {
+			best = i
+			continue
+		}
+		b := v.Findings[best]
+		if f.loses() != b.loses() {
+			if f.loses() {
+				best = i
+			}
+		} else if f.Confidence > b.Confidence {
+			best = i
+		}
+	}
+	if best >= 0 {
+		f := v.Findings[best]
+		v.Lost = f.loses()
+		v.Fragment, v.ForbiddenWord, v.Reason, v.Confidence = f.Fragment, f.ForbiddenWord, f.Reason, f.Confidence
+	}
+	return v
+}
+
+func (j *Judge) model() string {
+	if j.Model == "" {
+		return DefaultModel
+	}
+	return j.Model
+}
+
+// SaidForbidden tells if the description said contains a proscribed word of
+// the card. The local matcher catches the plain uses of the proscribed words,
+// otherwise a single model call looks for inflections, misspellings and
+// translations, checking the words which the local matcher found suspicious.
+func (j *Judge) SaidForbidden(ctx context.Context, card *Card, said string) (Verdict, error) {
+	local := matchLocally(card, said)
+	if v := verdictOf(card, local); v.Lost {
+		return v, nil
+	}
+	var hints string
+	for _, f := range local {
+		hints += fmt.Sprintf("\n\t\t- %q shares its stem with %q: is it an inflection, or an unrelated word?", f.Fragment, f.ForbiddenWord)
+	}
+	if hints != "" {
+		hints = "\n\t\tCheck these fragments, which look like inflections:" + hints + "\n"
+	}
+
+	systemInstruction := `
+		You are the judge in the Proscribed Words game.
+		The human player will say a description.
+
+		If the prompt contains any of the proscribed words, or an inflection of a forbidden
+		word, or a misspelling of a proscribed word, or a proscribed word translated in another
+		language, then the game is lost.
+
+		The proscribed words are:
+		` + strings.Join(card.Proscribed(), ", ") + `
+
+		List in "findings" every fragment of the prompt which looks like a proscribed word.
+		For each finding:
+		- "fragment" is the part of the prompt.
+		- "forbiddenWord" is exactly one of the original proscribed words.
+		- "reason" is "exact", "inflection", "misspelling", "translation", or "synonym"
+		  when the fragment only has a similar meaning.
+		- "confidence" is how sure you are of the reason, from 0 to 1.
+		If nothing looks like a proscribed word, "findings" is empty.
+
+		Synonyms of proscribed words must not trigger a lost game.
+
+		E.g. "ficelle" is a synonym of the proscribed word "Corde", because the two words have
+		a similar meaning but the word "ficelle" is not an inflection of the word "corde".
+
+		E.g. "orange" is a synonym of the proscribed word "Agrume", because the two words have
+		a similar meaning but the word "orange" is not an inflection of the word "Agrume".
+
+		E.g. "tronc" is a synonym of the proscribed word "Arbre", because the two words have
+		related meaning but the word "tronc" is not an inflection of the word "Arbre".
+
+		E.g. "poussent" is an inflection of the proscribed word "Pousser", because "poussent"
+		is a conjugation of the verb "Pousser".
+
+		E.g. "tree" is a translation of the proscribed word "Arbre".
+
+		E.g. "archer" is not an inflection of the proscribed word "Arch", even if it starts
+		with the same letters: the two words are unrelated.
+` + hints
+
+	// Force JSON structured output
+	zero, one := 0.0, 1.0
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromParts([]*genai.Part{
+			{Text: systemInstruction},
+		}, genai.RoleModel),
+		ResponseMIMEType: "application/json",
+		ResponseJsonSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"findings": {
+					Type:        genai.TypeArray,
+					Description: "The fragments of the prompt which look like proscribed words.",
+					Items: &genai.Schema{
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"fragment": {
+								Type:        genai.TypeString,
+								Description: "The part of the prompt.",
+							},
+							"forbiddenWord": {
+								Type:        genai.TypeString,
+								Description: "The proscribed word matched by the fragment.",
+							},
+							"reason": {
+								Type:        genai.TypeString,
+								Description: "How the fragment matches the proscribed word.",
+								Enum:        Reasons,
+							},
+							"confidence": {
+								Type:        genai.TypeNumber,
+								Description: "How sure the judge is of the reason.",
+								Minimum:     &zero,
+								Maximum:     &one,
+							},
+						},
+						Required: []string{"fragment", "forbiddenWord", "reason", "confidence"},
+					},
+				},
+			},
+			Required: []string{"findings"},
+		},
+	}
+
+	prompt := []*genai.Content{
+		genai.NewContentFromParts([]*genai.Part{
+			{Text: said},
+		}, genai.RoleUser),
+	}
+
+	resp, err := Generate(ctx, j.Client, j.model(), prompt, config)
+	if err != nil {
+		return Verdict{}, err
+	}
+	structureAnswer, err := TextOf(resp)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	var result struct {
+		Findings []Finding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(structureAnswer), &result); err != nil {
+		return Verdict{}, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	// The suspicious local findings are kept as notes, below MinConfidence
+	return verdictOf(card, append(result.Findings, local...)), nil
+}
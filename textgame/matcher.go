@@ -0,0 +1,97 @@
+package textgame
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LocalMatcher is the Referee which finds the proscribed words, without
+// calling a model. The words sharing a stem with a proscribed word are only
+// suspicious: it can't tell them from unrelated words. It misses the
+// translations and the misspellings.
+type LocalMatcher struct{}
+
+// stemConfidence is the confidence of the words which share the stem of a
+// proscribed word, below MinConfidence: a regular suffix is only a hint, as
+// "archer" is no inflection of "arch", nor "rid" of "ride".
+const stemConfidence = 0.3
+
+// SaidForbidden tells if the description said contains a proscribed word of the card.
+func (LocalMatcher) SaidForbidden(ctx context.Context, card *Card, said string) (Verdict, error) {
+	return verdictOf(card, matchLocally(card, said)), nil
+}
+
+// matchLocally returns the words of said which are proscribed words of the
+// card, regardless of case and accents, or which share their stem. The
+// proscribed words of several words match the same sequence of words.
+// Only the exact matches lose the game.
+func matchLocally(card *Card, said string) []Finding {
+	words := splitWords(said)
+	var findings []Finding
+	for _, proscribed := range card.Proscribed() {
+		pwords := splitWords(proscribed)
+		if len(pwords) == 0 {
+			continue
+		}
+		for i := 0; i+len(pwords) <= len(words); i++ {
+			reason := ReasonExact
+			for k, pw := range pwords {
+				w, p := Normalize(words[i+k]), Normalize(pw)
+				if w == p {
+					continue
+				}
+				if stem(w) == stem(p) {
+					reason = ReasonInflection
+					continue
+				}
+				reason = ""
+				break
+			}
+			if reason == "" {
+				continue
+			}
+			confidence := 1.0
+			if reason == ReasonInflection {
+				confidence = stemConfidence
+			}
+			findings = append(findings, Finding{
+				Fragment:      strings.Join(words[i:i+len(pwords)], " "),
+				ForbiddenWord: proscribed,
+				Reason:        reason,
+				Confidence:    confidence,
+			})
+		}
+	}
+	return findings
+}
+
+// splitWords splits s around anything other than letters and digits.
+func splitWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// suffixes are the regular inflections of English and French words, the
+// longest first.
+var suffixes = []string{
+	"ements", "ement", "ations", "ation", "aient", "ings", "ing", "ies", "ied",
+	"ait", "ant", "ent", "ons", "ers", "er", "es", "ed", "ez", "ée", "és", "s", "x", "e", "é",
+}
+
+// stem removes the longest regular suffix of the normalized word w, as long
+// as 3 letters remain. A final y becomes i, as in fly, flies.
+func stem(w string) string {
+	for _, suffix := range suffixes {
+		if st, ok := strings.CutSuffix(w, Normalize(suffix)); ok && utf8.RuneCountInString(st) >= 3 {
+			w = st
+			break
+		}
+	}
+	if st, ok := strings.CutSuffix(w, "y"); ok {
+		return st + "i"
+	}
+	return w
+}
@@ -0,0 +1,62 @@
+package textgame
+
+import (
+	"testing"
+
+	"github.com/Deleplace/verboten/deck"
+)
+
+func TestStem(t *testing.T) {
+	for _, tc := range []struct {
+		word, want string
+	}{
+		{"fly", "fli"},
+		{"flies", "fli"},
+		{"cats", "cat"},
+		{"bus", "bus"}, // too short to lose its s
+		{"archer", "arch"},
+		{"ride", "rid"},
+		{"stripes", "strip"},
+		{"numbers", "numb"},
+		{"ecrire", "ecrir"},
+		{"pizza", "pizza"},
+	} {
+		if got := stem(tc.word); got != tc.want {
+			t.Errorf("stem(%q) = %q, want %q", tc.word, got, tc.want)
+		}
+	}
+}
+
+func TestMatchLocally(t *testing.T) {
+	for _, tc := range []struct {
+		forbidden []string
+		said      string
+		// lost is whether the local verdict loses the game.
+		lost bool
+		// suspicious is whether a finding is reported without losing.
+		suspicious bool
+	}{
+		{[]string{"Fly"}, "it can fly high", true, false},
+		{[]string{"Fly"}, "It can FLY", true, false},
+		{[]string{"Écrire"}, "on peut ecrire avec", true, false},
+		{[]string{"Eau"}, "de l'EAU salée", true, false},
+		{[]string{"Ice Cream"}, "like an ice-cream in summer", true, false},
+		{[]string{"Ice Cream"}, "ice and cream", false, false},
+		{[]string{"Fly"}, "it flies", false, true},
+		{[]string{"Arch"}, "an archer shoots arrows", false, true},
+		{[]string{"Ride"}, "get rid of it", false, true},
+		{[]string{"Stripes"}, "a strip of land", false, true},
+		{[]string{"Fire"}, "a fir tree", false, true},
+		{[]string{"Numbers"}, "my fingers are numb", false, true},
+		{[]string{"Cap"}, "a daring caper", false, true},
+		{[]string{"Fly"}, "a butterfly", false, false},
+		{[]string{"Fly"}, "", false, false},
+	} {
+		card := &Card{Card: deck.Card{Word: "Target", Forbidden: tc.forbidden}}
+		v := verdictOf(card, matchLocally(card, tc.said))
+		if v.Lost != tc.lost || v.Suspicious() != tc.suspicious {
+			t.Errorf("%q with %q: lost %t, suspicious %t, want %t, %t (findings %+v)",
+				tc.said, tc.forbidden, v.Lost, v.Suspicious(), tc.lost, tc.suspicious, v.Findings)
+		}
+	}
+}
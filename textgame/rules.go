@@ -0,0 +1,149 @@
+package textgame
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The names of the rules.
+const (
+	RuleLetter    = "letter"
+	RuleMaxWords  = "maxwords"
+	RuleMaxLength = "maxlength"
+	RuleOneWord   = "oneword"
+)
+
+// Rule is a constraint on the descriptions, in addition to the proscribed
+// words of the card.
+type Rule interface {
+	// Check returns the violation of the rule by a turn of the describer, if any.
+	Check(turn string) *Violation
+	// String is the spec of the rule, see ParseRules.
+	String() string
+}
+
+// Violation is a description which broke a rule. The game frontends tell it
+// to the player, with a localized message for each rule.
+type Violation struct {
+	// Rule is the name of the rule.
+	Rule string `json:"rule"`
+	// Fragment is the part of the description which broke the rule.
+	Fragment string `json:"fragment"`
+	// Limit is the parameter of the rule: the letter, or the number of words or letters.
+	Limit string `json:"limit,omitempty"`
+}
+
+// ForbiddenLetter forbids the words containing a letter, as in a lipogram.
+type ForbiddenLetter struct {
+	Letter rune
+}
+
+func (r ForbiddenLetter) Check(turn string) *Violation {
+	letter := Normalize(string(r.Letter))
+	for _, w := range splitWords(turn) {
+		if strings.Contains(Normalize(w), letter) {
+			return &Violation{Rule: RuleLetter, Fragment: w, Limit: string(r.Letter)}
+		}
+	}
+	return nil
+}
+
+func (r ForbiddenLetter) String() string {
+	return RuleLetter + ":" + string(r.Letter)
+}
+
+// MaxWords limits the number of words of each turn. With 1, it is the
+// one-word clue of Password.
+type MaxWords struct {
+	N int
+}
+
+func (r MaxWords) Check(turn string) *Violation {
+	words := splitWords(turn)
+	if len(words) <= r.N {
+		return nil
+	}
+	rule := RuleMaxWords
+	if r.N == 1 {
+		rule = RuleOneWord
+	}
+	return &Violation{Rule: rule, Fragment: strings.Join(words, " "), Limit: strconv.Itoa(r.N)}
+}
+
+func (r MaxWords) String() string {
+	if r.N == 1 {
+		return RuleOneWord
+	}
+	return RuleMaxWords + ":" + strconv.Itoa(r.N)
+}
+
+// MaxLength forbids the words longer than N letters.
+type MaxLength struct {
+	N int
+}
+
+func (r MaxLength) Check(turn string) *Violation {
+	for _, w := range splitWords(turn) {
+		if utf8.RuneCountInString(w) > r.N {
+			return &Violation{Rule: RuleMaxLength, Fragment: w, Limit: strconv.Itoa(r.N)}
+		}
+	}
+	return nil
+}
+
+func (r MaxLength) String() string {
+	return RuleMaxLength + ":" + strconv.Itoa(r.N)
+}
+
+// ParseRules parses a comma-separated list of rules, e.g. "letter:e,maxwords:5":
+//
+//	letter:e     no word containing the letter e
+//	maxwords:5   at most 5 words per turn
+//	maxlength:6  no word longer than 6 letters
+//	oneword      one-word clues only
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for item := range strings.SplitSeq(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(item, ":")
+		name = strings.ToLower(name)
+		switch name {
+		case RuleLetter:
+			if utf8.RuneCountInString(arg) != 1 {
+				return nil, fmt.Errorf("rule %q needs a single letter", item)
+			}
+			r, _ := utf8.DecodeRuneInString(arg)
+			rules = append(rules, ForbiddenLetter{Letter: r})
+		case RuleMaxWords, RuleMaxLength:
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("rule %q needs a positive number", item)
+			}
+			if name == RuleMaxWords {
+				rules = append(rules, MaxWords{N: n})
+			} else {
+				rules = append(rules, MaxLength{N: n})
+			}
+		case RuleOneWord:
+			rules = append(rules, MaxWords{N: 1})
+		default:
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+	}
+	return rules, nil
+}
+
+// CheckRules returns the first violation of the rules by a turn, if any.
+func CheckRules(rules []Rule, turn string) *Violation {
+	for _, r := range rules {
+		if v := r.Check(turn); v != nil {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package textgame is the text mode of the game, shared by the command line
+// tools: the prompts of the AI guesser and describer, and the judge which
+// checks the descriptions for proscribed words.
+package textgame
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"google.golang.org/genai"
+
+	"github.com/Deleplace/verboten/deck"
+)
+
+// DefaultModel is the Gemini model used in text mode.
+const DefaultModel = "gemini-2.5-flash-lite"
+
+// LanguageNames are the languages supported in text mode, by language code.
+var LanguageNames = map[string]string{
+	"en": "English",
+	"fr": "French",
+}
+
+// GuesserInstructions are the system instructions of the AI guesser, by language code.
+var GuesserInstructions = map[string]string{
+	"fr": `
+				Tu es le devineur dans une partie de "Mots Prohibés".
+				Je vais te décrire un mot. Tu dois deviner ce que c'est.
+				Tu n'as que 3 essais.
+				Je connais le mot à faire deviner, mais je ne peux pas te le dire.
+				Je ne peux pas non plus te dire plusieurs mots prohibés.
+				Réponds uniquement en Français.
+				Réponds uniquement le mot que tu supposes être celui que j'essaie de faire deviner.
+				Commençons.
+				`,
+	"en": `
+				You are the guesser in a game of "Proscribed Words".
+				I will describe a word to you. You have to guess what it is.
+				You only have 3 guesses.
+				I know the word to guess, but I cannot say it to you.
+				I also cannot say several other proscribed words.
+				Answer only in English.
+				Answer only with the word you think is the one I'm trying to let you guess.
+				Let's start.
+				`,
+}
+
+// DescriberInstructions are the system instructions of an AI describer for
+// the given card. Each user message is the previous wrong guess, or empty
+// for the first clue.
+func DescriberInstructions(card *Card) string {
+	return fmt.Sprintf(`
+		You are the describer in a game of "Proscribed Words", in %s.
+		The word to describe is "%s".
+		Give one short clue at a time, so that the other player guesses the word.
+		You must never say the word itself, nor any of these proscribed words: %s.
+		Don't use inflections of these words, nor their translations in another language.
+		When the other player tells you a wrong guess, give a new, different clue.
+		Answer only with your clue.
+	`, card.LanguageName, card.Word, strings.Join(card.Forbidden, ", "))
+}
+
+// Card is a card played in text mode.
+type Card struct {
+	deck.Card
+	// LanguageName is the language of the card, e.g. "English".
+	LanguageName string
+}
+
+// IsWinning tells if the guess contains the word of the card, or one of its
+// accepted answers.
+func (c *Card) IsWinning(guess string) bool {
+	lowGuess := Normalize(guess)
+	for _, answer := range c.Answers() {
+		if strings.Contains(lowGuess, Normalize(answer)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize returns its argument lowercased and without diacritics
+func Normalize(s string) string {
+	// Local transformers, not shared with other goroutines
+	tr := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	normalized, _, err := transform.String(tr, strings.ToLower(s))
+	if err != nil {
+		// We do not expect string transformation to fail in general
+		panic(err)
+	}
+	return normalized
+}
+
+// NewClient creates a Gemini client. If baseURL is empty, the client is
+// configured by the usual environment variables (GOOGLE_API_KEY,
+// GOOGLE_GENAI_USE_VERTEXAI, ...). Otherwise the client uses the Gemini API
+// at baseURL, e.g. the local stand-in of cmd/standin.
+func NewClient(ctx context.Context, baseURL string) (*genai.Client, error) {
+	if baseURL == "" {
+		return genai.NewClient(ctx, &genai.ClientConfig{
+			// empty ClientConfig automatically uses the env vars
+		})
+	}
+	apiKey := os.Getenv("GOOGLE_API_KEY")
+	if apiKey == "" {
+		// The stand-in doesn't check the key
+		apiKey = "standin"
+	}
+	return genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{
+			BaseURL: baseURL,
+		},
+	})
+}
@@ -7,22 +7,43 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	_ "embed"
 
 	"github.com/gorilla/websocket"
 	"google.golang.org/genai"
+
+	"github.com/Deleplace/verboten/deck"
+	"github.com/Deleplace/verboten/textgame"
 )
 
 type VerbotenGameServer struct {
 	genaiClient *genai.Client
+	words       deck.Deck
+	games       *gameRegistry
+	rooms       *roomRegistry
+	decks       *deckStore
+	history     *history
+	daily       *dailyChallenges
+	archive     *gameArchive
+	// judge rules on the challenges of the players, in the rooms.
+	judge textgame.Referee
 }
 
 func NewServer(genaiClient *genai.Client) *VerbotenGameServer {
 	return &VerbotenGameServer{
 		genaiClient: genaiClient,
+		games:       newGameRegistry(),
+		rooms:       newRoomRegistry(),
+		decks:       newDeckStore(),
+		history:     newHistory(),
+		daily:       newDailyChallenges(),
+		archive:     newGameArchive(),
+		judge:       &textgame.Judge{Client: genaiClient, Model: textgame.DefaultModel},
 	}
 }
 
@@ -47,15 +68,85 @@ const guesserPrompt_ar = `
 	لا تقل أي شيء آخر غير الكلمة التي تخمنها.
 `
 
+var guesserPrompts = map[string]string{
+	"en": guesserPrompt,
+	"fr": guesserPrompt_fr,
+	"ar": guesserPrompt_ar,
+}
+
+// In reverse mode, the model describes the word and the human guesses.
+// The prompts are formatted with the secret word, and the proscribed words.
+
+const describerPrompt = `
+	You are playing the "guessing word" game, as the describer. The secret word is "%s".
+	Describe it aloud to the human player with short sentences, so that they can guess it.
+	You must never say the secret word, nor any of these proscribed words: %s.
+	Don't say them translated in another language, nor words with the same root.
+	The human player will say their guesses. If a guess is wrong, keep describing the word
+	differently. Don't say anything else than your description.
+`
+
+const describerPrompt_fr = `
+	Vous jouez au jeu du "mot à deviner", en tant que celui qui fait deviner. Le mot secret est "%s".
+	Décrivez-le à voix haute au joueur humain avec des phrases courtes, pour qu'il le devine.
+	Vous ne devez jamais dire le mot secret, ni aucun de ces mots prohibés : %s.
+	Ne les dites pas non plus traduits dans une autre langue, ni des mots de la même racine.
+	Le joueur humain dira ses suggestions. Si une suggestion est fausse, continuez à décrire le mot
+	autrement. Ne dites rien d'autre que votre description.
+`
+
+const describerPrompt_ar = `
+	أنت تلعب لعبة "تخمين الكلمات" بصفتك الواصف. الكلمة السرية هي "%s".
+	صفها بصوت عالٍ للاعب البشري بجمل قصيرة، حتى يتمكن من تخمينها.
+	يجب ألا تقول الكلمة السرية أبداً، ولا أياً من هذه الكلمات المحظورة: %s.
+	لا تقلها مترجمة إلى لغة أخرى، ولا كلمات من نفس الجذر.
+	سيقول اللاعب البشري تخميناته. إذا كان التخمين خاطئاً، استمر في وصف الكلمة بطريقة مختلفة.
+	لا تقل أي شيء آخر غير وصفك.
+`
+
+var describerPrompts = map[string]string{
+	"en": describerPrompt,
+	"fr": describerPrompt_fr,
+	"ar": describerPrompt_ar,
+}
+
+// describerKickoff is sent to the describer model when the round starts,
+// as it would otherwise wait for the human to speak first.
+const describerKickoff = "Start describing the word now."
+
 func (vg *VerbotenGameServer) Start(ctx context.Context) error {
 	log.SetFlags(0)
+	var err error
+	vg.words, err = deck.Load("assets/words.json")
+	if err != nil {
+		return err
+	}
+	// Without a history file, the card statistics start afresh at each restart
+	if filename := os.Getenv("VERBOTEN_HISTORY"); filename != "" {
+		if err := vg.history.open(filename); err != nil {
+			return err
+		}
+	}
+
 	http.HandleFunc("/", vg.serveGame)
 	http.HandleFunc("/live/", vg.liveGame)
+	http.HandleFunc("POST /api/rooms", vg.createRoom)
+	http.HandleFunc("GET /room/{code}", vg.serveRoom)
+	http.HandleFunc("GET /room/{code}/ws", vg.roomSocket)
+	http.HandleFunc("GET /watch/{gameID}", vg.watchGame)
+	http.HandleFunc("POST /api/decks", vg.uploadDeck)
+	http.HandleFunc("GET /api/decks/{deckID}/images/{image}", vg.serveDeckImage)
+	http.HandleFunc("GET /api/cards/stats", vg.serveCardStats)
+	http.HandleFunc("GET /daily/{lang}", vg.serveDaily)
+	http.HandleFunc("GET /api/daily/{lang}", vg.serveDailyResults)
+	http.HandleFunc("GET /api/games/{id}/debrief", vg.serveDebrief)
 	http.HandleFunc("/words.json", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "assets/words.json")
 	})
 	http.Handle("/forbiddenwords/", http.StripPrefix("/forbiddenwords/", http.FileServer(http.Dir("assets"))))
 
+	go vg.rooms.expireLoop(vg.games)
+
 	// Determine port for HTTP service.
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -71,13 +162,25 @@ func (vg *VerbotenGameServer) Start(ctx context.Context) error {
 //go:embed assets/verboten.html
 var gameWebapp string
 
+// gamePage is the data of the game page template.
+type gamePage struct {
+	Room  string // empty for a solo game
+	Lang  string
+	Daily bool // show the results of the daily challenge in Lang
+}
+
 func (vg *VerbotenGameServer) serveGame(w http.ResponseWriter, r *http.Request) {
+	vg.daily.identify(w, r)
+	vg.renderGame(w, gamePage{})
+}
+
+func (vg *VerbotenGameServer) renderGame(w http.ResponseWriter, page gamePage) {
 	tmpl, err := template.New("game").Parse(gameWebapp)
 	if err != nil {
 		http.Error(w, "Error loading template", http.StatusInternalServerError)
 		return
 	}
-	err = tmpl.Execute(w, nil)
+	err = tmpl.Execute(w, page)
 	if err != nil {
 		http.Error(w, "Error executing template", http.StatusInternalServerError)
 		return
@@ -92,41 +195,184 @@ var upgrader = websocket.Upgrader{
 
 func (vg *VerbotenGameServer) liveGame(w http.ResponseWriter, r *http.Request) {
 	lang := strings.TrimPrefix(r.URL.Path, "/live/")
-	var prompt string
-	switch lang {
-	case "en":
-		prompt = guesserPrompt
-	case "fr":
-		prompt = guesserPrompt_fr
-	case "ar":
-		prompt = guesserPrompt_ar
-	default:
+	prompt, ok := guesserPrompts[lang]
+	if !ok {
 		log.Printf("unsupported language: %q", lang)
 		http.NotFound(w, r)
 		return
 	}
 
+	var reverse, daily, speed bool
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", modeClassic:
+	case modeDaily:
+		daily = true
+	case modeSpeed:
+		speed = true
+	case modeReverse:
+		reverse = true
+		prompt = describerPrompts[lang]
+	default:
+		http.Error(w, "unsupported mode", http.StatusBadRequest)
+		return
+	}
+
+	rounds := defaultRounds
+	if s := r.URL.Query().Get("rounds"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid number of rounds", http.StatusBadRequest)
+			return
+		}
+		rounds = n
+	}
+	filter, err := requestFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rules, err := textgame.ParseRules(r.URL.Query().Get("rules"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(rules) > 0 && daily {
+		// The daily challenge is the same for everyone
+		http.Error(w, "rules are not supported in the daily challenge", http.StatusBadRequest)
+		return
+	}
+	deckID := r.URL.Query().Get("deck")
+	words, err := vg.deckFor(deckID, filter)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+	player := playerID(r)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var cards []deck.Card
+	var dailyPlayer string
+	if daily {
+		// Everyone plays the same card of the built-in deck, once a day
+		dailyPlayer = vg.daily.playerOf(r)
+		if dailyPlayer == "" {
+			http.Error(w, "missing daily challenge cookie", http.StatusBadRequest)
+			return
+		}
+		if vg.daily.played(lang, dailyPlayer) {
+			http.Error(w, "daily challenge already played today", http.StatusConflict)
+			return
+		}
+		var card deck.Card
+		card, err = dailyCard(vg.words, lang, today())
+		cards = []deck.Card{card}
+	} else if speed {
+		// As many cards as the players can describe in time
+		cards, err = words.Draw(lang, len(words[lang]), rng)
+	} else if deckID == "" && !reverse {
+		// The ratings are only known for the classic rounds of the built-in deck
+		cards, err = vg.history.drawFor(player, lang, words, rounds, rng)
+	} else {
+		cards, err = words.Draw(lang, rounds, rng)
+	}
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
 	c, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Fatal("upgrade error: ", err)
+		log.Println("upgrade error: ", err)
 		return
 	}
 	defer c.Close()
 
-	gameID := randomString(4)
-	forbiddenWords := r.URL.Query()["forbidden"]
-	log.Printf("Starting game %s in %s with proscribed words %q", gameID, lang, forbiddenWords)
+	// The attempt counts once the game is on
+	var attempt *dailyAttempt
+	if daily {
+		if attempt = vg.daily.start(lang, dailyPlayer); attempt == nil {
+			c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "daily challenge already played today"))
+			return
+		}
+	}
+
+	h := newHub()
+	defer h.leave(h.join(c))
+
+	m := &match{
+		vg:      vg,
+		lang:    lang,
+		prompt:  prompt,
+		reverse: reverse,
+		speed:   speed,
+		deckID:  deckID,
+		player:  player,
+		cards:   cards,
+		hub:     h,
+		rules:   rules,
+	}
+	if attempt != nil {
+		m.onVerdict = func(result roundResult) {
+			vg.daily.finish(attempt, result)
+		}
+	}
+	m.id = vg.games.add(m)
+	defer vg.games.remove(m.id)
+	defer m.closeSessions()
+	log.Printf("Starting game %s in %s with %d rounds (reverse: %t, speed: %t)", m.id, lang, len(cards), reverse, speed)
 
 	ctx := context.Background()
+	if err := m.startRound(ctx); err != nil {
+		log.Printf("Game %s: %v", m.id, err)
+		return
+	}
+
+	for {
+		// Human speech Loop:
+		// Receive audio  and transcript data from player browser, via WebSocket.
+		// Forward it to the model guesser player's Gemini Live session.
+		// Also forward it to the model judge's Gemini Live session.
+		// Between the audio, the browser tells when it is ready for the round
+		// timer to start, and when it wants the next card.
+		_, message, err := c.ReadMessage()
+		if err != nil {
+			log.Println("read from client error: ", err)
+			break
+		}
 
-	var model string
+		var msg clientMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			log.Println("unmarshal message error ", string(message), err)
+			break
+		}
+		switch {
+		case msg.Ready:
+			m.startTimer()
+		case msg.NextRound:
+			err = m.nextRound(ctx)
+		default:
+			m.sendRealtimeInput(msg.LiveRealtimeInput)
+		}
+		if err != nil {
+			log.Printf("Game %s: %v", m.id, err)
+			break
+		}
+	}
+}
+
+// liveModel is the Gemini Live model used by the guesser and by the judge.
+func (vg *VerbotenGameServer) liveModel() string {
 	if vg.genaiClient.ClientConfig().Backend == genai.BackendVertexAI {
-		model = "gemini-live-2.5-flash-preview-native-audio-09-2025"
-	} else {
-		model = "gemini-2.5-flash-native-audio-preview-09-2025"
+		return "gemini-live-2.5-flash-preview-native-audio-09-2025"
 	}
+	return "gemini-2.5-flash-native-audio-preview-09-2025"
+}
 
-	// Gemini Live session 1 : model listens to the human and guesses the secret word
+// connectPlayer opens the Gemini Live session where the model plays with the
+// human: it listens to the human and guesses the secret word, or in reverse
+// mode it describes the secret word and listens to the human's guesses.
+func (vg *VerbotenGameServer) connectPlayer(ctx context.Context, prompt string) (*genai.Session, error) {
 	config := &genai.LiveConnectConfig{}
 	config.SystemInstruction = &genai.Content{
 		Parts: []*genai.Part{
@@ -154,94 +400,32 @@ func (vg *VerbotenGameServer) liveGame(w http.ResponseWriter, r *http.Request) {
 			SilenceDurationMs:        &shortDuration,
 		},
 	}
-	session, err := vg.genaiClient.Live.Connect(ctx, model, config)
-	if err != nil {
-		log.Fatal("connect to model error: ", err)
-	}
-	defer session.Close()
+	return vg.genaiClient.Live.Connect(ctx, vg.liveModel(), config)
+}
 
-	// Gemini Live session 2 : model listens to the human and guesses the secret word
+// connectJudge opens the Gemini Live session where the model listens to
+// the describer and calls out any proscribed word. The describer is the human,
+// or in reverse mode the AI player whose transcript is sent to the judge as text.
+func (vg *VerbotenGameServer) connectJudge(ctx context.Context, forbiddenWords []string, reverse bool) (*genai.Session, error) {
+	describer := "a human player"
+	if reverse {
+		describer = "an AI player"
+	}
 	configJudge := &genai.LiveConnectConfig{}
 	configJudge.SystemInstruction = &genai.Content{
 		Parts: []*genai.Part{
 			{Text: `
-				You're a judge listening to a human player of Proscribed Words, who is not allowed to
-				say any of the words from the proscribed list. If the human player says any of them,
+				You're a judge listening to ` + describer + ` of Proscribed Words, who is not allowed to
+				say any of the words from the proscribed list. If the player says any of them,
 				or a very close word with the same radical, or one of the words translated in aother
-				language, then pronounce only the phrase from the human that violated the rule.
+				language, then pronounce only the phrase from the player that violated the rule.
 
 				The proscribed words are: ` + strings.Join(forbiddenWords, ", ")},
 		},
 	}
 	configJudge.ResponseModalities = []genai.Modality{genai.ModalityAudio}
 	configJudge.OutputAudioTranscription = &genai.AudioTranscriptionConfig{}
-	sessionJudge, err := vg.genaiClient.Live.Connect(ctx, model, configJudge)
-	if err != nil {
-		log.Fatal("connect to model error: ", err)
-	}
-	defer sessionJudge.Close()
-
-	go func() {
-		// Guessing Loop:
-		// Receive audio data from the Gemini Live session.
-		// Forward it to the player browser, via WebSocket.
-		for {
-			message, err := session.Receive()
-			if err != nil {
-				log.Println("guesser model deconnected: ", err)
-				return
-			}
-			messageBytes, err := json.Marshal(message)
-			if err != nil {
-				log.Fatal("marshal guesser model response error: ", message, err)
-			}
-			err = c.WriteMessage(websocket.TextMessage, messageBytes)
-			if err != nil {
-				log.Println("write message error: ", err)
-				break
-			}
-		}
-	}()
-
-	for {
-		// Human speech Loop:
-		// Receive audio  and transcript data from player browser, via WebSocket.
-		// Forward it to the model guesser player's Gemini Live session.
-		// Also forward it to the model judge's Gemini Live session.
-		_, message, err := c.ReadMessage()
-		if err != nil {
-			log.Println("read from client error: ", err)
-			break
-		}
-
-		var realtimeInput genai.LiveRealtimeInput
-		if err := json.Unmarshal(message, &realtimeInput); err != nil {
-			log.Fatal("unmarshal message error ", string(message), err)
-		}
-		session.SendRealtimeInput(realtimeInput)
-		sessionJudge.SendRealtimeInput(realtimeInput)
-	}
-
-	go func() {
-		// Judge Loop:
-		// Receive audio and transcript data from the Gemini Live session.
-		// Signal to the browser to end the game.
-		for {
-			message, err := sessionJudge.Receive()
-			if err != nil {
-				log.Println("judge deconnected: ", err)
-				return
-			}
-			sc := message.ServerContent
-			if sc != nil {
-				ot := sc.OutputTranscription
-				if ot != nil {
-					log.Printf("Game %s Judge says %q", gameID, ot.Text)
-					// TODO err = c.WriteMessage(websocket.TextMessage, messageBytes)
-				}
-			}
-		}
-	}()
+	return vg.genaiClient.Live.Connect(ctx, vg.liveModel(), configJudge)
 }
 
 const alphanum = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
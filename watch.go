@@ -0,0 +1,85 @@
+package verboten
+
+import (
+	"log"
+	"net/http"
+	"sync"
+)
+
+// watchable is an ongoing game that spectators can watch: a solo match, or a room.
+type watchable interface {
+	// gameHub is where the events of the game are published.
+	gameHub() *hub
+	// snapshot returns the events that bring a new spectator up to date.
+	snapshot() []gameEvent
+}
+
+// gameRegistry holds the ongoing games, by game ID.
+type gameRegistry struct {
+	mu    sync.Mutex
+	games map[string]watchable
+}
+
+func newGameRegistry() *gameRegistry {
+	return &gameRegistry{
+		games: make(map[string]watchable),
+	}
+}
+
+// add registers g under a new random game ID, and returns the ID.
+func (gr *gameRegistry) add(g watchable) string {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	id := randomString(4)
+	for gr.games[id] != nil {
+		id = randomString(4)
+	}
+	gr.games[id] = g
+	return id
+}
+
+func (gr *gameRegistry) get(id string) watchable {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	return gr.games[id]
+}
+
+func (gr *gameRegistry) remove(id string) {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	delete(gr.games, id)
+}
+
+// watchGame is a read-only WebSocket streaming the events of an ongoing game:
+// cards, transcripts, guesses, verdicts, timer and outcome.
+// The spectators share the hub of the game, so they cost no extra model calls.
+func (vg *VerbotenGameServer) watchGame(w http.ResponseWriter, r *http.Request) {
+	gameID := r.PathValue("gameID")
+	g := vg.games.get(gameID)
+	if g == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	c, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("upgrade error: ", err)
+		return
+	}
+	defer c.Close()
+
+	h := g.gameHub()
+	client := h.join(c)
+	defer h.leave(client)
+	for _, event := range g.snapshot() {
+		h.sendTo(client, event)
+	}
+	log.Printf("Game %s has a new spectator", gameID)
+
+	for {
+		// Spectators can't influence the game: whatever they send is discarded.
+		if _, _, err := c.ReadMessage(); err != nil {
+			break
+		}
+	}
+}